@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"os/exec"
 	"strings"
+	"time"
 
 	"npf.io/gorram/run"
 )
@@ -61,9 +62,29 @@ type UI struct {
 	Regen    bool
 	Template string
 	Cache    string
+	Parsers  []string
+	From     string
+	Quoted   bool
+	EnvArgs  bool
+	Timeout  time.Duration
+	Src      string
+	Dst      string
 	Args     []string
 }
 
+// stringsFlag is a flag.Value that may be specified multiple times, collecting
+// each value.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
 // Parse converts the gorram command line.  If an error is returned, the program
 // should exit with the code specified by the error's Code() int function.
 func Parse(env OSEnv) (*UI, error) {
@@ -73,7 +94,18 @@ func Parse(env OSEnv) (*UI, error) {
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
 	fs.BoolVar(&ui.Regen, "r", false, "")
 	fs.StringVar(&ui.Template, "t", "", "")
-	if err := fs.Parse(env.Args[1:]); err != nil {
+	fs.Var((*stringsFlag)(&ui.Parsers), "p", "")
+	fs.StringVar(&ui.From, "from", "", "")
+	fs.BoolVar(&ui.Quoted, "q", false, "")
+	fs.BoolVar(&ui.EnvArgs, "env", false, "")
+	fs.DurationVar(&ui.Timeout, "timeout", 0, "")
+	fs.StringVar(&ui.Src, "src", "", "")
+	fs.StringVar(&ui.Dst, "dst", "", "")
+	args, err := expandResponseFiles(env.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
 	if ui.Template != "" {
@@ -89,6 +121,45 @@ func Parse(env OSEnv) (*UI, error) {
 	return ui, nil
 }
 
+// valueFlags are gorram's flags that take a value as the next arg.
+var valueFlags = map[string]bool{"t": true, "p": true, "from": true, "timeout": true, "src": true, "dst": true}
+
+// expandResponseFiles replaces each @file arg given before the package with the
+// lines of the file, one arg per line, so long command lines can be kept in a
+// file.  The file may hold the package, function, and its args too, but not
+// other response files.  Args after the package are the function's, where
+// @file is handled by the script.
+func expandResponseFiles(args []string) ([]string, error) {
+	args = append([]string(nil), args...)
+	// args before this index came from a response file.
+	fromFile := 0
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case i >= fromFile && len(arg) > 1 && arg[0] == '@' && arg[1] != '@':
+			b, err := ioutil.ReadFile(arg[1:])
+			if err != nil {
+				return nil, fmt.Errorf("can't read response file: %v", err)
+			}
+			s := strings.TrimSuffix(strings.Replace(string(b), "\r\n", "\n", -1), "\n")
+			var lines []string
+			if s != "" {
+				lines = strings.Split(s, "\n")
+			}
+			args = append(args[:i], append(lines, args[i+1:]...)...)
+			fromFile = i + len(lines)
+			i--
+		case arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-"):
+			// the package, after which the args are the function's.
+			return args, nil
+		case valueFlags[strings.TrimLeft(arg, "-")]:
+			// skip the flag's value.
+			i++
+		}
+	}
+	return args, nil
+}
+
 func parseCommand(ui *UI, env OSEnv) (*run.Command, error) {
 	if len(ui.Args) < 2 {
 		return nil, errors.New(usage)
@@ -97,6 +168,13 @@ func parseCommand(ui *UI, env OSEnv) (*run.Command, error) {
 		Args:     ui.Args[2:],
 		Regen:    ui.Regen,
 		Template: ui.Template,
+		Parsers:  ui.Parsers,
+		From:     ui.From,
+		Quoted:   ui.Quoted,
+		EnvArgs:  ui.EnvArgs,
+		Timeout:  ui.Timeout,
+		Src:      ui.Src,
+		Dst:      ui.Dst,
 		Package:  ui.Args[0],
 		Cache:    ui.Cache,
 		Env: run.Env{
@@ -105,7 +183,15 @@ func parseCommand(ui *UI, env OSEnv) (*run.Command, error) {
 			Stdin:  env.Stdin,
 		},
 	}
-	parts := strings.Split(ui.Args[1], ".")
+	name := ui.Args[1]
+	if i := strings.Index(name, "["); i != -1 {
+		if !strings.HasSuffix(name, "]") {
+			return nil, fmt.Errorf("Command %q invalid. Expected type args in brackets, like slices.Max[int].", name)
+		}
+		cmd.TypeArgs = splitTypeArgs(name[i+1 : len(name)-1])
+		name = name[:i]
+	}
+	parts := strings.Split(name, ".")
 	switch len(parts) {
 	case 1:
 		cmd.Function = parts[0]
@@ -119,25 +205,134 @@ func parseCommand(ui *UI, env OSEnv) (*run.Command, error) {
 	return cmd, nil
 }
 
+// splitTypeArgs splits the type args given in brackets on the commas between
+// them, but not on the commas inside them, e.g. in func(int, int) bool.
+func splitTypeArgs(s string) []string {
+	var args []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(s[start:]))
+}
+
 const usage = `Usage:
 gorram [OPTION] <pkg> <func | var.method> [args...]
 
 Options:
-  -t <string>  format output with a go template
-  -h, --help   display this help
+  -t <string>     format output with a go template
+  -p <func>       parse args with this function when there's more than one
+                  choice, may be repeated, and may be given as param=func
+  --from <format> decode an interface{} arg from stdin, format must be json
+  -q              unquote args given as Go quoted strings, like "\t"
+  -env            read args given as env:NAME from the environment variable
+  --timeout <dur> cancel a context.Context arg after this long, e.g. 30s
+  --src <param>   read this param from stdin or a file, rather than guessing
+  --dst <param>   write this param's output to stdout, rather than guessing
+  -h, --help      display this help
 
 Executes a go function or an method on a global variable defined in a package in
 the stdlib or a package in your GOPATH.  Package must be the full package import
 path, e.g. encoding/json.  Only exported functions, methods, and variables may
 be called.
 
-Most builtin types are supported, and streams of input (via io.Reader or []byte
-for example) may be read from stdin.  If specified as an argument, the argument
-to a stream input is expected to be a filename.
+Most builtin types are supported, as are types that implement
+encoding.TextUnmarshaler, and types with a parse function in their package, like
+regexp.Compile or url.Parse.  Slice arguments may be given as a comma separated
+list, or by repeating --name=value for the parameter's name, and variadic
+arguments take all the remaining arguments.  Values given with --name=value and
+variadic arguments aren't split on commas, so use those for values with commas
+in them.  Map arguments may be given as key=value pairs separated by commas, by
+repeating --name=key=value, or as a JSON object.  Struct arguments may be given
+as JSON, as @ and the name of a file holding JSON, or as nil for pointers, and
+their exported fields may be set with --Field=value (or --param.Field=value).
+If nothing else reads from stdin, a struct argument that isn't given may be read
+from stdin as JSON.  Interface and pointer arguments may be given as the name of
+a package level variable or a function without arguments in a package the
+function's package uses, or in the package the name gives, like
+base64.URLEncoding or sha256.New, or as nil.  Func arguments may be given as the
+name of a function, like unicode.IsSpace.  Streams
+of input (a []byte, io.Reader, io.ReadSeeker, io.ReaderAt, io.RuneReader,
+*bufio.Reader, or *os.File, for example) may be read from stdin, which is copied
+to a temp file first if the stream needs to seek.  If specified as an argument,
+the argument to a stream input is expected to be a filename, or - for stdin.  A
+function that takes just a string, like strings.ToUpper, reads it from stdin if
+it isn't given.  The stream input is the first one named src, r, in, body, or
+data, or else the first one there is, and the output argument is the one named
+dst, w, wr, out, or writer.  Use --src or --dst with a parameter's name when
+these guesses are wrong.  Any other stream inputs are read from files given as
+arguments, and one of them may be - for stdin instead of the first, e.g. gorram
+bytes Equal a.txt - < b.txt.  Note that this means []byte arguments after the
+first stream input, like the subslice of bytes.Contains, are filenames, not the
+bytes themselves.  To give the bytes, use hex: or base64:, or -q and a quoted
+string, e.g. gorram -q bytes Contains a.txt '"ell"'.
+
+Arguments may be given by the name of the parameter, as --name=value or --name
+value, mixed with positional arguments, e.g. --indent='  '.  Unknown names and
+names given more than once (other than for slices) are errors.  Use -- before
+positional arguments that start with --.
+
+Any argument may instead be given as a Go expression by putting -e before it,
+e.g. -e 'http.Dir(".")' or -e '[]int{3,1,2}'.  The expression is type checked
+against the parameter, and may use the packages the function's package uses.
+Arguments after -- are never expressions.
+
+Arguments of type context.Context are supplied automatically, rather than given
+on the command line.  The context is cancelled on SIGINT or SIGTERM, or when the
+--timeout is up.
+
+Generic functions take their type arguments in brackets after the function's
+name, e.g. gorram slices Max[int] 3,1,2.  Type arguments may be left out if they
+can be inferred from the others, as slices.Max's S ~[]E can from E, or from the
+types of -e expressions, so slices.Max[int] is slices.Max[[]int, int].  Type
+arguments may use the packages the function's package uses.
+
+Arguments may also be read indirectly: @path is the contents of the file, with
+-env, env:NAME is the environment variable, and - is stdin, less one trailing
+newline.  Only one argument may be read from stdin.  Use @@ for a value that
+starts with @, or -e '"-"' for a literal -.  Before the package, @file is a
+response file, whose lines are each an argument to gorram, so long command lines
+may be kept in a file, e.g. gorram @args.txt.
+
+With -q, arguments given as Go quoted strings are unquoted, so escapes like "\t"
+or "\u00e9" work in any shell.  []byte and [N]byte arguments other than the
+first stream input may also be given as hex: or base64: and the encoded bytes,
+e.g. hex:7f000001.  A byte array argument must be exactly the size of the array.
+
+Interface{} arguments are inferred from what they look like: a number, bool,
+quoted string, JSON object or array, or nil, and otherwise a string.  With
+--from json, an interface{} argument that isn't given is decoded from stdin, so
+e.g. encoding/json MarshalIndent can reformat JSON.
 
 Return values are printed to stdout.  If the function has an output argument,
-like io.Reader or *bytes.Buffer, it is automatically passed in and then written
-to stdout.
+like io.Writer or *bytes.Buffer, it is automatically passed in and then written
+to stdout.  An output argument may also be an *os.File, *bufio.Writer (which is
+flushed), *strings.Builder, io.WriteCloser (which is closed), or
+io.StringWriter.  A []byte output argument, as in encoding/hex Encode, is made
+the size given by the EncodedLen or DecodedLen function (or method) beside the
+function, and only the number of bytes it returns are written.  A []byte
+return value, like encoding/json MarshalIndent's, is written as is too.  A
+newline is added to the output only if it doesn't already end with one.
+
+If the function returns a writer that wraps its output argument, like
+compress/gzip NewWriter or encoding/base64 NewEncoder, stdin (or a file given
+after the function's arguments) is copied through it to stdout, and then it is
+closed, or flushed for a *bufio.Writer.  No newline is added to output piped
+this way.  If it returns a hash.Hash, like crypto/sha256 New, the input is
+copied into it and the sum is printed in hex.
+If it returns a reader, like compress/gzip NewReader or io.LimitReader, what it
+reads is copied to stdout as is, and then it is closed if it is an io.Closer.
+An *os.File, like os.Create returns, is printed rather than read.
 
 If there's no output stream, the return value is simply written to stdout via
 fmt.Println.  If the return value is a struct that has an exported field that is
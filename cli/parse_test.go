@@ -50,6 +50,189 @@ func TestParseAndRun(t *testing.T) {
 	}
 }
 
+func TestParseParsers(t *testing.T) {
+	t.Parallel()
+	env := OSEnv{
+		Args: []string{"gorram", "-p", "CompilePOSIX", "-p", "u=ParseRequestURI", "regexp", "MatchString"},
+		Env:  map[string]string{},
+	}
+	ui, err := Parse(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"CompilePOSIX", "u=ParseRequestURI"}
+	if strings.Join(ui.Parsers, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected parsers %q but got %q", expected, ui.Parsers)
+	}
+	if len(ui.Args) != 2 {
+		t.Errorf("Expected 2 args but got %q", ui.Args)
+	}
+}
+
+func TestParseFrom(t *testing.T) {
+	t.Parallel()
+	env := OSEnv{
+		Args: []string{"gorram", "--from", "json", "encoding/json", "Marshal"},
+		Env:  map[string]string{},
+	}
+	ui, err := Parse(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ui.From != "json" {
+		t.Errorf("Expected from %q but got %q", "json", ui.From)
+	}
+	if len(ui.Args) != 2 {
+		t.Errorf("Expected 2 args but got %q", ui.Args)
+	}
+}
+
+func TestParseQuoted(t *testing.T) {
+	t.Parallel()
+	env := OSEnv{
+		Args: []string{"gorram", "-q", "encoding/json", "Indent", `"\t"`},
+		Env:  map[string]string{},
+	}
+	ui, err := Parse(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ui.Quoted {
+		t.Error("Expected quoted to be set")
+	}
+	if len(ui.Args) != 3 {
+		t.Errorf("Expected 3 args but got %q", ui.Args)
+	}
+}
+
+func TestParseEnvArgs(t *testing.T) {
+	t.Parallel()
+	env := OSEnv{
+		Args: []string{"gorram", "-env", "strings", "Repeat", "x", "env:COUNT"},
+		Env:  map[string]string{},
+	}
+	ui, err := Parse(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ui.EnvArgs {
+		t.Error("Expected env args to be set")
+	}
+	if len(ui.Args) != 4 {
+		t.Errorf("Expected 4 args but got %q", ui.Args)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	t.Parallel()
+	env := OSEnv{
+		Args: []string{"gorram", "--timeout", "1m30s", "net", "DefaultResolver.LookupHost", "example.com"},
+		Env:  map[string]string{},
+	}
+	ui, err := Parse(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ui.Timeout != 90*time.Second {
+		t.Errorf("Expected timeout %v but got %v", 90*time.Second, ui.Timeout)
+	}
+	if len(ui.Args) != 3 {
+		t.Errorf("Expected 3 args but got %q", ui.Args)
+	}
+}
+
+func TestParseSrcDst(t *testing.T) {
+	t.Parallel()
+	env := OSEnv{
+		Args: []string{"gorram", "--src", "body", "--dst=w", "net/http", "Post", "http://example.com", "text/plain"},
+		Env:  map[string]string{},
+	}
+	ui, err := Parse(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ui.Src != "body" {
+		t.Errorf("Expected src %q but got %q", "body", ui.Src)
+	}
+	if ui.Dst != "w" {
+		t.Errorf("Expected dst %q but got %q", "w", ui.Dst)
+	}
+	c, err := parseCommand(ui, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Src != "body" || c.Dst != "w" {
+		t.Errorf("Expected src %q and dst %q but got %q and %q", "body", "w", c.Src, c.Dst)
+	}
+}
+
+func TestParseTypeArgs(t *testing.T) {
+	t.Parallel()
+	ui := &UI{Args: []string{"slices", "SortFunc[[]string, string]", "a,b", "strings.Compare"}}
+	c, err := parseCommand(ui, OSEnv{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Function != "SortFunc" {
+		t.Errorf("Expected function %q but got %q", "SortFunc", c.Function)
+	}
+	expected := []string{"[]string", "string"}
+	if strings.Join(c.TypeArgs, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected type args %q but got %q", expected, c.TypeArgs)
+	}
+	if _, err := parseCommand(&UI{Args: []string{"slices", "Max[int"}}, OSEnv{}); err == nil {
+		t.Error("Expected an error for unclosed type args but got none")
+	}
+}
+
+func TestSplitTypeArgs(t *testing.T) {
+	t.Parallel()
+	tests := map[string][]string{
+		"int":                                {"int"},
+		"[]string, string":                   {"[]string", "string"},
+		"map[string]int,func(a, b int) bool": {"map[string]int", "func(a, b int) bool"},
+	}
+	for s, expected := range tests {
+		if got := splitTypeArgs(s); strings.Join(got, "|") != strings.Join(expected, "|") {
+			t.Errorf("splitTypeArgs(%q): expected %q but got %q", s, expected, got)
+		}
+	}
+}
+
+func TestParseResponseFile(t *testing.T) {
+	t.Parallel()
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("-p\r\nCompilePOSIX\nregexp\nMatchString\n\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	env := OSEnv{
+		Args: []string{"gorram", "-t", "@" + f.Name(), "@" + f.Name(), "@" + f.Name()},
+		Env:  map[string]string{},
+	}
+	ui, err := Parse(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the template isn't a response file, nor are the function's args.
+	if ui.Template != "@"+f.Name() {
+		t.Errorf("Expected template %q but got %q", "@"+f.Name(), ui.Template)
+	}
+	expected := []string{"regexp", "MatchString", "", "@" + f.Name()}
+	if strings.Join(ui.Args, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected args %q but got %q", expected, ui.Args)
+	}
+	if strings.Join(ui.Parsers, " ") != "CompilePOSIX" {
+		t.Errorf("Expected parsers %q but got %q", "CompilePOSIX", ui.Parsers)
+	}
+}
+
 // func Now() Time
 // tests zero arg Function.
 // Tests printing of value with ToString method.
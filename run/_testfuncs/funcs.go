@@ -1,7 +1,300 @@
 package testfuncs
 
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
 // DoubleUint64 uses a uint64 as an argument for testing purposes. It returns 2x
 // the argument.
 func DoubleUint64(a uint64) uint64 {
 	return a * 2
 }
+
+// Celsius is a named type with a basic underlying type for testing purposes.
+type Celsius float64
+
+// ToFahrenheit uses a named type as an argument for testing purposes. It
+// returns the temperature converted to fahrenheit.
+func ToFahrenheit(c Celsius) float64 {
+	return float64(c)*9/5 + 32
+}
+
+// Unescaped uses a type from html/template, which has the same name as the
+// text/template package the script imports, for testing purposes.  It returns
+// h as a plain string.
+func Unescaped(h template.HTML) string {
+	return string(h)
+}
+
+// DaysIn uses a named type from another package that has constants declared
+// for it, for testing purposes.  It returns the number of days in the month.
+func DaysIn(m time.Month, year int) int {
+	return time.Date(year, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// FindString uses a type from another package that must be created with a
+// parser function, for testing purposes.  It returns the leftmost match of re
+// in s.
+func FindString(re *regexp.Regexp, s string) string {
+	return re.FindString(s)
+}
+
+// UTCDate uses a time.Time argument for testing purposes.  It returns the date
+// of t in UTC.
+func UTCDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// AddTo uses time.Time and time.Duration arguments for testing purposes.  It
+// returns t+d in UTC, formatted as RFC3339.
+func AddTo(t time.Time, d time.Duration) string {
+	return t.Add(d).UTC().Format(time.RFC3339)
+}
+
+// Dozen is a typed constant of a basic type for testing purposes.
+const Dozen int = 12
+
+// Sum uses a variadic argument that needs conversion, for testing purposes. It
+// returns the sum of the numbers.
+func Sum(nums ...int) int {
+	total := 0
+	for _, n := range nums {
+		total += n
+	}
+	return total
+}
+
+// AppendBytes uses a variadic byte argument for testing purposes.  It returns
+// data with extra appended.
+func AppendBytes(data []byte, extra ...byte) string {
+	return string(append(data, extra...))
+}
+
+// Lookup uses a map argument for testing purposes.  It returns the value for
+// the key in the map.
+func Lookup(m map[string]int, key string) int {
+	return m[key]
+}
+
+// Header is a named map type whose values are lists, for testing purposes.
+type Header map[string][]string
+
+// Values uses a named map argument for testing purposes.  It returns the
+// values for the key in the header.
+func Values(h Header, key string) []string {
+	return h[key]
+}
+
+// Point is a struct type for testing purposes.
+type Point struct {
+	X, Y  int
+	Label string
+	Tags  []string
+}
+
+// Describe uses a struct argument for testing purposes.  It returns a
+// description of the point.
+func Describe(p Point) string {
+	return fmt.Sprintf("%s(%d,%d)%v", p.Label, p.X, p.Y, p.Tags)
+}
+
+// Scale uses a pointer to struct argument for testing purposes.  It returns a
+// description of the point scaled by factor, or nil if p is nil.
+func Scale(p *Point, factor int) string {
+	if p == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%s(%d,%d)", p.Label, p.X*factor, p.Y*factor)
+}
+
+// Greeter is an interface type for testing purposes.
+type Greeter interface {
+	Greet() string
+}
+
+type english struct{}
+
+func (english) Greet() string { return "hello" }
+
+type french struct{}
+
+func (french) Greet() string { return "bonjour" }
+
+// English is a global that implements Greeter for testing purposes.
+var English Greeter = english{}
+
+// NewFrench is a constructor that returns a Greeter for testing purposes.
+func NewFrench() Greeter {
+	return french{}
+}
+
+// NewKlingon is a constructor that fails for testing purposes.
+func NewKlingon() (Greeter, error) {
+	return nil, errors.New("no klingon speakers available")
+}
+
+// Greet uses an interface argument for testing purposes.  It returns the
+// greeting, or silence if g is nil.
+func Greet(g Greeter) string {
+	if g == nil {
+		return "silence"
+	}
+	return g.Greet()
+}
+
+// Encode uses a pointer argument that is best given as a global variable, for
+// testing purposes.  It returns s encoded with enc.
+func Encode(enc *base64.Encoding, s string) string {
+	return enc.EncodeToString([]byte(s))
+}
+
+// TypeOf uses an interface{} argument for testing purposes.  It returns the
+// type and value of v.
+func TypeOf(v interface{}) string {
+	return fmt.Sprintf("%T %v", v, v)
+}
+
+// Number is a constraint for testing generic functions.
+type Number interface {
+	int | float64
+}
+
+// Total is a generic function without a core type for testing purposes.  It
+// returns the sum of the numbers.
+func Total[N Number](nums ...N) N {
+	var sum N
+	for _, n := range nums {
+		sum += n
+	}
+	return sum
+}
+
+// Temps is a named slice type for testing generic functions.
+type Temps []Celsius
+
+// Last is a generic function whose type params are tied by a core type for
+// testing purposes.  It returns the last element of s.
+func Last[S ~[]E, E any](s S) E {
+	return s[len(s)-1]
+}
+
+// Pick is a generic function with a non-generic param for testing purposes.
+// It returns a if first is true, otherwise b.
+func Pick[T any](first bool, a, b T) T {
+	if first {
+		return a
+	}
+	return b
+}
+
+// Sleep uses a context argument for testing purposes.  It returns done after d,
+// or the context's error if it's cancelled first.
+func Sleep(ctx context.Context, d time.Duration) (string, error) {
+	select {
+	case <-time.After(d):
+		return "done", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Tail uses an io.ReadSeeker argument for testing purposes.  It returns the
+// last n bytes of r.
+func Tail(r io.ReadSeeker, n int64) (string, error) {
+	if _, err := r.Seek(-n, io.SeekEnd); err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(r)
+	return string(b), err
+}
+
+// ByteAt uses an io.ReaderAt argument for testing purposes.  It returns the
+// byte at off in r.
+func ByteAt(r io.ReaderAt, off int64) (string, error) {
+	b := make([]byte, 1)
+	if _, err := r.ReadAt(b, off); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// FileSize uses an *os.File argument for testing purposes.  It returns the
+// number of bytes read from f.
+func FileSize(f *os.File) (int64, error) {
+	return io.Copy(io.Discard, f)
+}
+
+// FirstLine uses a *bufio.Reader argument for testing purposes.  It returns
+// the first line read from r.
+func FirstLine(r *bufio.Reader) (string, error) {
+	s, err := r.ReadString('\n')
+	return strings.TrimSuffix(s, "\n"), err
+}
+
+// FileWrite uses an *os.File dst for testing purposes.  It writes s to dst.
+func FileWrite(dst *os.File, s string) error {
+	_, err := dst.WriteString(s)
+	return err
+}
+
+// BufioWrite uses a *bufio.Writer dst for testing purposes.  It writes s to
+// dst without flushing it.
+func BufioWrite(dst *bufio.Writer, s string) error {
+	_, err := dst.WriteString(s)
+	return err
+}
+
+// BuilderWrite uses a *strings.Builder dst for testing purposes.  It writes s
+// to dst.
+func BuilderWrite(dst *strings.Builder, s string) {
+	dst.WriteString(s)
+}
+
+// CloserWrite uses an io.WriteCloser dst for testing purposes.  It writes s to
+// dst, which must be closed afterward.
+func CloserWrite(dst io.WriteCloser, s string) error {
+	_, err := io.WriteString(dst, s)
+	return err
+}
+
+// StringWrite uses an io.StringWriter dst for testing purposes.  It writes s
+// to dst.
+func StringWrite(dst io.StringWriter, s string) error {
+	_, err := dst.WriteString(s)
+	return err
+}
+
+// Prefix uses an io.Reader named r and an io.Writer named w for testing
+// purposes.  It writes prefix and then what's read from r to w.
+func Prefix(prefix string, r io.Reader, w io.Writer) error {
+	if _, err := io.WriteString(w, prefix); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, r)
+	return err
+}
+
+// Concat uses two io.Readers for testing purposes, the second of which is
+// named as a src.  It returns what's read from head followed by what's read
+// from body.
+func Concat(head, body io.Reader) (string, error) {
+	b, err := io.ReadAll(io.MultiReader(head, body))
+	return string(b), err
+}
+
+// Emit uses an io.Writer with a name that isn't guessed to be a dst for testing
+// purposes.  It writes s to sink.
+func Emit(sink io.Writer, s string) error {
+	_, err := io.WriteString(sink, s)
+	return err
+}
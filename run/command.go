@@ -2,18 +2,24 @@
 package run // import "npf.io/gorram/run"
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
 	"go/types"
+	"hash/fnv"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
 
 	"golang.org/x/tools/go/loader"
 )
@@ -21,26 +27,31 @@ import (
 // version is the string that stamps the generated files. If the files should
 // change, you must change the version.  The actual format of the version
 // doesn't matter, as long as it's different from earlier versions, but it's
-// nice to keep it in <semver>  <timestamp> format so that it has some human
+// nice to keep it a semver that only goes up, so that it has some human
 // meaning.
-const version = "0.9.2  2017-06-17 23:02:43.896500743"
+const version = "0.28.7"
 
 // Used for type comparison.
 // These are ok to keep global since they're static.
 var byteSliceType = types.NewSlice(types.Typ[types.Byte])
 var stringType = types.Typ[types.String]
 var errorType = types.Universe.Lookup("error").Type()
+var anyType = types.NewInterfaceType(nil, nil).Complete()
 
 // Command contains the definition of a command that gorram can execute. It
 // represents a package and either global function or a method call on a global
 // variable.  If GlobalVar is non-empty, it's the latter.
 type Command struct {
-	// Args contains the arguments to the function.
+	// Args contains the arguments to the function.  An arg of -e means the
+	// next arg is a Go expression to use as the value of a param.
 	Args []string
 	// Package the function exists in.
 	Package string
 	// Function (or method) to call.
 	Function string
+	// TypeArgs are the Go types to instantiate a generic function with, e.g.
+	// int for slices.Max[int].  Any that aren't given are inferred.
+	TypeArgs []string
 	// GlobalVar, if not empty, indicates a global variable to call, and means
 	// Function is a method on that variable.
 	GlobalVar string
@@ -53,6 +64,29 @@ type Command struct {
 	// Template, if non-empty, contains the Go template with which to format the
 	// output.
 	Template string
+	// Parsers holds the names of the functions that should be used to convert
+	// arguments when more than one function in a type's package can do it
+	// (e.g. regexp.Compile and regexp.CompilePOSIX).  Each entry is either the
+	// function name, or paramname=function to choose it for just one param.
+	Parsers []string
+	// From, if non-empty, is the format (e.g. json) in which to decode an
+	// interface{} argument from stdin.
+	From string
+	// Quoted, if true, means args given as Go quoted strings, like "\t", are
+	// unquoted.
+	Quoted bool
+	// EnvArgs, if true, means args given as env:NAME are read from the
+	// environment variable NAME.
+	EnvArgs bool
+	// Timeout, if non-zero, is how long until the context.Context passed to the
+	// function is cancelled.
+	Timeout time.Duration
+	// Src, if non-empty, is the name of the param to read from stdin or a file,
+	// rather than the one guessed from the params' names and types.
+	Src string
+	// Dst, if non-empty, is the name of the param whose output is written to
+	// stdout, rather than the one guessed from the params' names and types.
+	Dst string
 	// Env contains the input and output streams the command should read from
 	// and write to.
 	Env Env
@@ -68,16 +102,34 @@ type Command struct {
 	errorType    types.Type
 	ioReaderType types.Type
 	ioWriterType types.Type
+	durationType types.Type
+	timeType     types.Type
+	contextType  types.Type
 
 	// Used for types.Implements.
-	ioReader *types.Interface
-	ioWriter *types.Interface
+	ioReader        *types.Interface
+	ioWriter        *types.Interface
+	textUnmarshaler *types.Interface
 
 	// used for finding code to put into the script.
 	argConverters []converter
 	dstHandlers   []dstHandler
 	srcHandlers   []srcHandler
 	retHandlers   []retHandler
+
+	// inStruct holds the struct types whose converters are being created, so
+	// that self-referential structs don't recurse forever.
+	inStruct []*types.Named
+
+	// byName holds the import paths of the packages the script imports by
+	// their own names, and aliases the names it imports the others by, e.g.
+	// html_template, so that packages with the same name don't collide.
+	byName  map[string]bool
+	aliases map[string]string
+
+	// parserNames holds the names of the parsers that may be chosen with -p
+	// for the types whose converters have been created.
+	parserNames []string
 }
 
 // Env encapsulates the externalities of the environment in which a command is
@@ -94,25 +146,69 @@ func Run(c *Command) error {
 	if err != nil {
 		return err
 	}
-	return c.run(path, c.Template)
+	return c.run(path)
 }
 
-func (c *Command) run(path, template string) error {
+func (c *Command) run(path string) error {
 	// put a -- between the filename and the args so we don't confuse go run
 	// into thinking the first arg is another file to run.
-	args := append([]string{"run", path, "--"}, c.Args...)
+	args := append([]string{"run", path, "--"}, c.scriptArgs()...)
 	cmd := exec.Command("go", args...)
 	cmd.Stdin = c.Env.Stdin
 	cmd.Stderr = c.Env.Stderr
 	cmd.Stdout = c.Env.Stdout
-	if template != "" {
-		cmd.Env = append([]string{"GORRAM_TEMPLATE=" + template}, os.Environ()...)
+	var env []string
+	if c.Template != "" {
+		env = append(env, "GORRAM_TEMPLATE="+c.Template)
+	}
+	if len(c.Parsers) > 0 {
+		env = append(env, "GORRAM_PARSERS="+strings.Join(c.Parsers, ","))
+	}
+	if c.From != "" {
+		env = append(env, "GORRAM_FROM="+c.From)
+	}
+	if c.Quoted {
+		env = append(env, "GORRAM_QUOTED=1")
+	}
+	if c.EnvArgs {
+		env = append(env, "GORRAM_ENV_ARGS=1")
+	}
+	if c.Timeout != 0 {
+		env = append(env, "GORRAM_TIMEOUT="+c.Timeout.String())
 	}
+	// the flags given this time win over any left in the environment, so
+	// the script never sees a stale setting from the user's shell.
+	for _, v := range os.Environ() {
+		name, _, _ := strings.Cut(v, "=")
+		if !scriptEnv[name] {
+			cmd.Env = append(cmd.Env, v)
+		}
+	}
+	cmd.Env = append(cmd.Env, env...)
 	return cmd.Run()
 }
 
+// scriptEnv holds the environment variables gorram passes its flags to the
+// script in.
+var scriptEnv = map[string]bool{
+	"GORRAM_TEMPLATE": true,
+	"GORRAM_PARSERS":  true,
+	"GORRAM_FROM":     true,
+	"GORRAM_QUOTED":   true,
+	"GORRAM_ENV_ARGS": true,
+	"GORRAM_TIMEOUT":  true,
+}
+
 // Generate creates the gorram .go file for the given command.
 func (c *Command) Generate() (path string, err error) {
+	if _, err := c.cliArgs(); err != nil {
+		return "", err
+	}
+	// the script only checks --from if it reads from stdin, so check it here
+	// before we use a cached script.
+	if c.From != "" && c.From != "json" {
+		return "", fmt.Errorf("unknown format %q for --from, expected json", c.From)
+	}
 	path = c.script()
 	if !c.Regen {
 		if fileVersionOK(path) {
@@ -121,10 +217,16 @@ func (c *Command) Generate() (path string, err error) {
 	}
 	// let's see if this is even a valid package
 	imports := map[string]bool{
-		"io":    false,
-		"bytes": false,
+		"context":  false,
+		"io":       false,
+		"bytes":    false,
+		"encoding": false,
+		"time":     false,
 	}
 	imports[c.Package] = false
+	for _, path := range c.argImports() {
+		imports[path] = false
+	}
 	conf := loader.Config{
 		ImportPkgs: imports,
 	}
@@ -195,6 +297,10 @@ func (c *Command) initTypes() {
 	c.ioWriterType = c.prog.Package("io").Pkg.Scope().Lookup("Writer").Type()
 	c.ioReader = c.ioReaderType.Underlying().(*types.Interface)
 	c.ioWriter = c.ioWriterType.Underlying().(*types.Interface)
+	c.durationType = c.prog.Package("time").Pkg.Scope().Lookup("Duration").Type()
+	c.timeType = c.prog.Package("time").Pkg.Scope().Lookup("Time").Type()
+	c.contextType = c.prog.Package("context").Pkg.Scope().Lookup("Context").Type()
+	c.textUnmarshaler = c.prog.Package("encoding").Pkg.Scope().Lookup("TextUnmarshaler").Type().Underlying().(*types.Interface)
 
 	// we do these here so they are definitely performed after we initialize
 	// some of the types they depend on.
@@ -279,13 +385,40 @@ type templateData struct {
 	StdinToSrc   string
 	DstInit      string
 	DstToStdout  string
+	HasSrc       bool
+	Pipe         string
 	PrintVal     string
-	ParamTypes   map[types.Type]struct{}
 	Imports      map[string]struct{}
 	ArgConvFuncs []string
 	ArgInits     []string
+	ArgSpecs     []string
+	SrcArg       int
+	SrcValue     bool
+	Usage        string
+	HasContext   bool
+	// Aliases holds the names of the imports that aren't imported by their
+	// package's name, by path.
+	Aliases map[string]string
+
+	cmd       *Command
+	usageArgs []usageArg
+	// exprs holds the Go expressions given for params, by param index.
+	exprs map[int]string
+	// byteDst is true if the dst is a []byte, which is only written to stdout
+	// up to the count of bytes the function returns.
+	byteDst bool
+}
 
-	cmd *Command
+// usageArg describes one CLI arg for the script's usage message.
+type usageArg struct {
+	Name     string
+	Type     string
+	Desc     string
+	Optional bool
+	Variadic bool
+	// Flag, if true, means this is a --Field flag for a struct param, and so
+	// is not shown as a positional arg.
+	Flag bool
 }
 
 func (c *Command) compileData() (templateData, error) {
@@ -295,16 +428,32 @@ func (c *Command) compileData() (templateData, error) {
 	}
 	// guaranteed to work per types.Cloud docs.
 	sig := f.Type().(*types.Signature)
+	fn := c.Function
+	var targImports []string
+	switch {
+	case sig.TypeParams().Len() > 0:
+		var targs []types.Type
+		sig, targs, targImports, err = c.instantiate(f)
+		if err != nil {
+			return templateData{}, err
+		}
+		targNames := make([]string, len(targs))
+		for i, t := range targs {
+			targNames[i] = c.scriptType(t)
+		}
+		fn += "[" + strings.Join(targNames, ", ") + "]"
+	case len(c.TypeArgs) > 0:
+		return templateData{}, fmt.Errorf("%s.%s is not generic, but was given type args", c.Package, c.Function)
+	}
 
 	data := templateData{
-		Version:    version,
-		PkgName:    c.pkg().Name(),
-		Func:       c.Function,
-		GlobalVar:  c.GlobalVar,
-		HasLen:     hasLen(sig.Results()),
-		SrcIdx:     -1,
-		DstIdx:     -1,
-		ParamTypes: map[types.Type]struct{}{},
+		Version:   version,
+		PkgName:   c.pkg().Name(),
+		Func:      fn,
+		GlobalVar: c.GlobalVar,
+		HasLen:    hasLen(sig.Results()),
+		SrcIdx:    -1,
+		DstIdx:    -1,
 		Imports: map[string]struct{}{
 			c.Package: {},
 			"log":     {},
@@ -312,50 +461,93 @@ func (c *Command) compileData() (templateData, error) {
 		},
 		cmd: c,
 	}
-	if err := data.parseResults(sig.Results()); err != nil {
+	for _, imp := range targImports {
+		data.Imports[imp] = struct{}{}
+	}
+	dst, src, err := c.checkSrcDst(sig)
+	if err != nil {
+		return templateData{}, err
+	}
+	if src == -1 {
+		var imports []string
+		data.Pipe, imports, _ = c.pipe(sig, dst)
+		for _, imp := range imports {
+			data.Imports[imp] = struct{}{}
+		}
+	}
+	data.exprs, err = c.bindExprs(sig.Params(), sig.Variadic(), dst, src, data.Pipe != "")
+	if err != nil {
 		return templateData{}, err
 	}
-	if dst, src, ok := c.checkSrcDst(sig.Params()); ok {
-		if err := data.setSrcDst(dst, src, sig.Params()); err != nil {
+	for x, expr := range data.exprs {
+		p := sig.Params().At(x)
+		imports, err := c.checkExpr(expr, paramName(p, x), p.Type())
+		if err != nil {
 			return templateData{}, err
 		}
-	} else {
-
+		for _, imp := range imports {
+			data.Imports[imp] = struct{}{}
+		}
+	}
+	if _, isExpr := data.exprs[src]; isExpr {
+		// the src is given by an expression, so there's nothing to read.
+		src = -1
+	}
+	if err := data.setSrcDst(dst, src, sig.Params()); err != nil {
+		return templateData{}, err
+	}
+	if err := data.parseResults(sig.Results()); err != nil {
+		return templateData{}, err
 	}
-	if err := data.parseParams(sig.Params()); err != nil {
+	if err := data.parseParams(sig.Params(), sig.Variadic()); err != nil {
 		return templateData{}, err
 	}
-	data.NumCLIArgs = sig.Params().Len()
+	data.NumCLIArgs = sig.Params().Len() - len(data.exprs)
+	if data.Pipe != "" {
+		// the input to pipe through the function's result.
+		data.NumCLIArgs++
+	}
 	if data.DstIdx != -1 {
 		data.NumCLIArgs--
 	}
+	for x := 0; x < sig.Params().Len(); x++ {
+		if c.isContext(sig.Params().At(x).Type()) {
+			data.NumCLIArgs--
+		}
+	}
+	if data.HasContext {
+		for _, imp := range []string{"context", "log", "os", "os/signal", "syscall", "time"} {
+			data.Imports[imp] = struct{}{}
+		}
+	}
+	if data.NumCLIArgs > 0 {
+		// used by bindArgs.
+		data.Imports["fmt"] = struct{}{}
+		data.Imports["io"] = struct{}{}
+		data.Imports["os"] = struct{}{}
+		data.Imports["strconv"] = struct{}{}
+		data.Imports["strings"] = struct{}{}
+		data.Imports["unicode"] = struct{}{}
+	}
+	if err := c.checkParsers(); err != nil {
+		return templateData{}, err
+	}
+	if len(c.parserNames) > 0 {
+		data.addConverter(converter{Funcs: []string{fmt.Sprintf(parserNamesDecl, c.parserNames)}})
+		sort.Strings(data.ArgConvFuncs)
+	}
+	data.Aliases = c.aliases
 	return data, nil
 }
 
 func (data *templateData) setSrcDst(dst, src int, params *types.Tuple) error {
 	data.SrcIdx = src
 	data.DstIdx = dst
-	srcType := params.At(src).Type()
-	srcH, ok := data.cmd.srcHandler(srcType)
-	if !ok {
-		return fmt.Errorf("should be impossible: src type %q has no handler", srcType)
-	}
-
-	// if src position is, as per usual, after dst, e.g. func f(dst, src []byte)
-	// then the cli arg position will actually be one less than that, since we
-	// don't pass in the dst arg from the CLI. e.g. in the above, dst is 0, src
-	// is 1, but for the cli args, src would be arg 0.
-	srcArg := src
-	if dst != -1 && src > dst {
-		srcArg--
-	}
-	data.ArgsToSrc = fmt.Sprintf(srcH.ArgToSrc, srcArg)
-	data.StdinToSrc = srcH.StdinToSrc
-	for _, imp := range srcH.Imports {
-		data.Imports[imp] = struct{}{}
+	if src != -1 {
+		if err := data.setSrc(params.At(src).Type()); err != nil {
+			return err
+		}
 	}
-	data.SrcInit = srcH.Init
-
 	if dst == -1 {
 		return nil
 	}
@@ -364,70 +556,450 @@ func (data *templateData) setSrcDst(dst, src int, params *types.Tuple) error {
 	if !ok {
 		return fmt.Errorf("should be impossible: dst type %q has no handler", dstType)
 	}
+	if types.Identical(dstType, byteSliceType) {
+		// the dst is sized from the length of the src.
+		if src == -1 || !(isString(params.At(src).Type()) || types.Identical(params.At(src).Type(), byteSliceType)) {
+			return fmt.Errorf("%s.%s's []byte dst is sized from its src, which must be a []byte or string", data.cmd.Package, data.cmd.Function)
+		}
+		data.byteDst = true
+	}
 	data.DstInit = dstH.Init
 	data.DstToStdout = dstH.ToStdout
+	if data.Pipe == "" {
+		data.DstToStdout += dstH.EndLine
+	}
+	// the declarations may be shared with the result's handler.
+	data.addConverter(converter{Funcs: dstH.Decls})
 	for _, imp := range dstH.Imports {
 		data.Imports[imp] = struct{}{}
 	}
 	return nil
 }
 
-func (data *templateData) parseParams(params *types.Tuple) error {
+func (data *templateData) setSrc(srcType types.Type) error {
+	srcH, ok := data.srcHandler(srcType)
+	if !ok {
+		return fmt.Errorf("should be impossible: src type %q has no handler", srcType)
+	}
+
+	data.HasSrc = true
+	data.ArgsToSrc = srcH.ArgToSrc
+	data.SrcValue = srcH.Value
+	data.StdinToSrc = srcH.StdinToSrc
+	for _, imp := range srcH.Imports {
+		data.Imports[imp] = struct{}{}
+	}
+	data.SrcInit = srcH.Init
+	return nil
+}
+
+// parseParams figures out how to get each of the function's params from the
+// CLI args.  Each CLI arg is bound to a param at runtime by the script's
+// bindArgs function, using the specs we generate here, and then converted to
+// the param's type.
+func (data *templateData) parseParams(params *types.Tuple, variadic bool) error {
 	pos := 0
 	var args []string
+	// a struct param can be read from stdin if nothing else is.
+	stdinFree := data.SrcIdx == -1 && data.Pipe == ""
 	for x := 0; x < params.Len(); x++ {
+		p := params.At(x)
+		name := paramName(p, x)
+		isVariadic := variadic && x == params.Len()-1
 		if x == data.SrcIdx {
-			args = append(args, "src")
+			src := "src"
+			if isVariadic {
+				src = "src..."
+			}
+			args = append(args, src)
+			// the cli arg position may be before the param's, e.g. in func
+			// f(dst, src []byte), since we don't pass in the dst, contexts,
+			// or expressions from the CLI.
+			data.SrcArg = len(data.ArgSpecs)
+			h, _ := data.srcHandler(p.Type())
+			data.ArgSpecs = append(data.ArgSpecs, fmt.Sprintf("{name: %q, optional: true, raw: %t}", name, !h.Value))
+			data.usageArgs = append(data.usageArgs, usageArg{
+				Name:     name,
+				Type:     typeString(p.Type()),
+				Desc:     h.Desc,
+				Optional: true,
+			})
 			continue
 		}
 		if x == data.DstIdx {
 			args = append(args, "dst")
 			continue
 		}
-		p := params.At(x)
-		t := p.Type()
-		conv, ok := data.cmd.argConverter(t)
+		if data.cmd.isContext(p.Type()) {
+			// contexts are supplied by the script, and aren't CLI args.
+			args = append(args, "ctx")
+			data.HasContext = true
+			continue
+		}
+		if expr, ok := data.exprs[x]; ok {
+			// the param would still have been the one read from stdin when
+			// the expressions were bound, so it has to be here too.
+			if conv, ok := data.cmd.argConverter(p.Type()); ok && stdinFree {
+				stdinFree = !conv.Struct && (isVariadic || !types.Identical(p.Type(), anyType))
+			}
+			if isVariadic {
+				expr += "..."
+			}
+			args = append(args, expr)
+			continue
+		}
+		conv, ok := data.cmd.argConverter(p.Type())
+		isStream := false
+		switch h, isSrc := data.cmd.srcHandler(p.Type()); {
+		case isVariadic:
+			// each arg is converted to one element, even for ...byte, whose
+			// []byte converter would take the bytes of a single arg.
+			conv, ok = data.cmd.sliceConverter(p.Type().(*types.Slice))
+		case isSrc && h.Stream.Expr != "" && data.Pipe == "":
+			// streams other than the src are read from files too.
+			conv, ok, isStream = h.Stream, true, true
+		}
 		if !ok {
 			return fmt.Errorf("don't understand how to convert arg %q from CLI", p.Name())
 		}
-		args = append(args, fmt.Sprintf("arg%d", pos+1))
-		data.ParamTypes[t] = struct{}{}
-		data.ArgInits = append(data.ArgInits, fmt.Sprintf(conv.Assign, pos+1, pos))
+		arg := usageArg{
+			Name:     name,
+			Type:     typeString(p.Type()),
+			Desc:     conv.Usage,
+			Variadic: isVariadic,
+		}
+		// lists get all the values given for the param, other types just get
+		// the one.
+		spec := len(data.ArgSpecs)
+		vals := fmt.Sprintf("vals[%d][0]", spec)
+		switch {
+		case isStream:
+			// the arg is a filename, so it isn't read indirectly.
+			data.ArgSpecs = append(data.ArgSpecs, fmt.Sprintf("{name: %q, raw: true}", name))
+		case stdinFree && !isVariadic && types.Identical(p.Type(), anyType):
+			// with --from, this param is decoded from stdin if it isn't given.
+			data.ArgSpecs = append(data.ArgSpecs, fmt.Sprintf("{name: %q, optional: os.Getenv(\"GORRAM_FROM\") != \"\"}", name))
+			vals = fmt.Sprintf("vals[%d]", spec)
+			conv.Expr = "argToAnyOrStdin(%[1]s, %[2]s)"
+			conv.Funcs = append(conv.Funcs, argToAnyOrStdinFunc)
+			conv.Imports = append(conv.Imports, "encoding/json", "os")
+			arg.Desc = joinDesc(conv.Usage, "or stdin with --from json")
+			arg.Optional = true
+			stdinFree = false
+		case conv.Struct:
+			fields := make([]string, len(conv.Fields))
+			for i, f := range conv.Fields {
+				fields[i] = strconv.Quote(f.Name)
+			}
+			data.ArgSpecs = append(data.ArgSpecs, fmt.Sprintf("{name: %q, optional: true, list: true, noSplit: true, fields: []string{%s}}", name, strings.Join(fields, ", ")))
+			vals = fmt.Sprintf("vals[%d]", spec)
+			if stdinFree {
+				vals = fmt.Sprintf("orStdin(vals[%d])", spec)
+				data.addConverter(converter{
+					Imports: []string{"bytes", "io"},
+					Funcs:   []string{orStdinFunc},
+				})
+				arg.Desc = joinDesc(conv.Usage, "or stdin")
+				stdinFree = false
+			}
+			arg.Optional = true
+		case isVariadic:
+			data.ArgSpecs = append(data.ArgSpecs, fmt.Sprintf("{name: %q, list: true, variadic: true}", name))
+			vals = fmt.Sprintf("vals[%d]", spec)
+			arg.Type = "..." + typeString(p.Type().(*types.Slice).Elem())
+			arg.Desc = joinDesc("zero or more values", conv.Usage)
+		case conv.Multi && conv.NoSplit:
+			data.ArgSpecs = append(data.ArgSpecs, fmt.Sprintf("{name: %q, list: true, noSplit: true}", name))
+			vals = fmt.Sprintf("vals[%d]", spec)
+			arg.Desc = joinDesc(conv.Usage, fmt.Sprintf("or repeat --%s=<value>", name))
+		case conv.Multi:
+			data.ArgSpecs = append(data.ArgSpecs, fmt.Sprintf("{name: %q, list: true}", name))
+			vals = fmt.Sprintf("vals[%d]", spec)
+			arg.Desc = joinDesc(fmt.Sprintf("comma separated, or repeat --%s=<value>", name), conv.Usage)
+		default:
+			data.ArgSpecs = append(data.ArgSpecs, fmt.Sprintf("{name: %q}", name))
+		}
+		data.usageArgs = append(data.usageArgs, arg)
+		for _, f := range conv.Fields {
+			data.usageArgs = append(data.usageArgs, usageArg{
+				Name: "--" + f.Name,
+				Type: f.Type,
+				Desc: f.Desc,
+				Flag: true,
+			})
+		}
+
+		argName := fmt.Sprintf("arg%d", pos+1)
+		expr := fmt.Sprintf(conv.Expr, vals, strconv.Quote(name))
+		data.ArgInits = append(data.ArgInits, fmt.Sprintf("%s := %s", argName, expr))
+		if isVariadic {
+			argName += "..."
+		}
+		args = append(args, argName)
+		data.addConverter(conv)
 		pos++
 	}
-	data.Args = strings.Join(args, ", ")
-	for t := range data.ParamTypes {
-		converter, _ := data.cmd.argConverter(t)
-		data.ArgConvFuncs = append(data.ArgConvFuncs, converter.Func)
-		for _, imp := range converter.Imports {
-			data.Imports[imp] = struct{}{}
+	if data.Pipe != "" {
+		// the input piped through the function's result is read like a src,
+		// given after the function's args.
+		if err := data.setSrc(data.cmd.ioReaderType); err != nil {
+			return err
 		}
+		data.SrcArg = len(data.ArgSpecs)
+		data.ArgSpecs = append(data.ArgSpecs, `{name: "src", optional: true, raw: true}`)
+		data.usageArgs = append(data.usageArgs, usageArg{
+			Name:     "src",
+			Type:     typeString(data.cmd.ioReaderType),
+			Desc:     srcFileDesc,
+			Optional: true,
+		})
 	}
+	data.Args = strings.Join(args, ", ")
+	data.setUsage()
 
 	// sort so we have consistent output.
 	sort.Strings(data.ArgConvFuncs)
 	return nil
 }
 
-func (c *Command) checkSrcDst(params *types.Tuple) (dst, src int, ok bool) {
+// setUsage creates the usage message the script prints when it is given the
+// wrong arguments.
+func (data *templateData) setUsage() {
+	name := data.Func
+	if data.GlobalVar != "" {
+		name = data.GlobalVar + "." + data.Func
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "Usage: gorram %s %s", data.cmd.Package, name)
+	for _, arg := range data.usageArgs {
+		switch {
+		case arg.Flag:
+		case arg.Variadic:
+			fmt.Fprintf(buf, " [%s...]", arg.Name)
+		case arg.Optional:
+			fmt.Fprintf(buf, " [%s]", arg.Name)
+		default:
+			fmt.Fprintf(buf, " <%s>", arg.Name)
+		}
+	}
+	fmt.Fprintln(buf)
+	if len(data.usageArgs) > 0 {
+		fmt.Fprint(buf, "\nArguments:\n")
+		table := &bytes.Buffer{}
+		w := tabwriter.NewWriter(table, 0, 4, 2, ' ', 0)
+		for _, arg := range data.usageArgs {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", arg.Name, arg.Type, arg.Desc)
+		}
+		w.Flush()
+		// args without a description would otherwise end in padding.
+		for _, line := range strings.SplitAfter(table.String(), "\n") {
+			fmt.Fprint(buf, strings.TrimRight(line, " \n"))
+			if strings.HasSuffix(line, "\n") {
+				fmt.Fprintln(buf)
+			}
+		}
+		fmt.Fprintf(buf, "\nArguments may also be given by name, e.g. --%s=<value>.\n", data.usageArgs[0].Name)
+	}
+	data.Usage = buf.String()
+}
+
+// joinDesc joins the non-empty parts of an arg's description for the usage
+// message.
+func joinDesc(parts ...string) string {
+	var desc []string
+	for _, p := range parts {
+		if p != "" {
+			desc = append(desc, p)
+		}
+	}
+	return strings.Join(desc, "; ")
+}
+
+// addConverter records the helper functions and imports a converter needs.
+// Several converters may share a helper function (e.g. named types with the
+// same underlying type), so each function is only added once.
+func (data *templateData) addConverter(conv converter) {
+	for _, imp := range conv.Imports {
+		data.Imports[imp] = struct{}{}
+	}
+outer:
+	for _, fn := range conv.Funcs {
+		for _, f := range data.ArgConvFuncs {
+			if f == fn {
+				continue outer
+			}
+		}
+		data.ArgConvFuncs = append(data.ArgConvFuncs, fn)
+	}
+}
+
+// paramName returns the name of the parameter for use in error messages. Not
+// all parameters have names, so for those we fall back to their position.
+func paramName(p *types.Var, idx int) string {
+	if p.Name() == "" || p.Name() == "_" {
+		return fmt.Sprintf("#%d", idx+1)
+	}
+	return p.Name()
+}
+
+// pipe returns the code that pipes the input from stdin or a file through the
+// value the function returns, and the imports it needs, if the function
+// returns a writer that wraps its dst, like gzip.NewWriter, or a hash.Hash,
+// like sha256.New, whose sum is printed in hex.
+func (c *Command) pipe(sig *types.Signature, dst int) (code string, imports []string, ok bool) {
+	results := sig.Results()
+	switch {
+	case results.Len() == 1:
+	case results.Len() == 2 && types.Identical(results.At(1).Type(), errorType):
+	default:
+		return "", nil, false
+	}
+	t := results.At(0).Type()
+	const copyCode = `
+	if _, err := io.Copy(val, src); err != nil {
+		log.Fatal(err)
+	}
+`
+	if hashPkg := c.prog.Package("hash"); hashPkg != nil && dst == -1 {
+		hash := hashPkg.Pkg.Scope().Lookup("Hash").Type().Underlying().(*types.Interface)
+		if types.Implements(t, hash) {
+			return copyCode + `
+	fmt.Printf("%x\n", val.Sum(nil))
+`, []string{"fmt", "io", "log"}, true
+		}
+	}
+	if dst == -1 || !types.Implements(t, c.ioWriter) {
+		return "", nil, false
+	}
+	code = copyCode
+	// writers like gzip's must be closed (or flushed, like bufio's) to write
+	// everything out.
+	for _, name := range []string{"Close", "Flush"} {
+		if hasMethod(t, name) {
+			code += fmt.Sprintf(`
+	if err := val.%s(); err != nil {
+		log.Fatal(err)
+	}
+`, name)
+			break
+		}
+	}
+	return code, []string{"io", "log"}, true
+}
+
+// hasMethod reports whether t has a method called name that takes nothing and
+// returns an error.
+func hasMethod(t types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name)
+	f, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := f.Type().(*types.Signature)
+	return sig.Params().Len() == 0 && sig.Results().Len() == 1 && types.Identical(sig.Results().At(0).Type(), errorType)
+}
+
+// dstNames and srcNames are the names functions commonly give the streams
+// they write output to and read input from.
+var (
+	dstNames = []string{"dst", "w", "wr", "out", "writer"}
+	srcNames = []string{"src", "r", "in", "body", "data"}
+)
+
+// checkSrcDst returns the indexes of the params that are written to stdout
+// (dst) and read from stdin or a file (src), or -1 if there isn't one.  The
+// dst must have one of the dstNames, and the src is the first param with one
+// of the srcNames, or else the first that can be read from a stream at all.
+// Command.Dst and Command.Src override these guesses.
+func (c *Command) checkSrcDst(sig *types.Signature) (dst, src int, err error) {
+	params := sig.Params()
 	dst, src = -1, -1
-	for x := 0; x < params.Len(); x++ {
-		p := params.At(x)
-		switch p.Name() {
-		case "dst":
-			if c.isDstType(p.Type()) {
+	if c.Dst != "" {
+		dst, err = c.streamParam(params, c.Dst, "--dst", c.isDstType)
+		if err != nil {
+			return -1, -1, err
+		}
+	} else {
+		for x := 0; x < params.Len(); x++ {
+			p := params.At(x)
+			if hasName(p, dstNames) && c.isDstType(p.Type()) {
 				dst = x
+				break
 			}
-		default:
-			if src == -1 && c.isSrcType(p.Type()) {
-				src = x
+		}
+	}
+	if c.Src != "" {
+		src, err = c.streamParam(params, c.Src, "--src", c.isSrcType)
+		if err != nil {
+			return -1, -1, err
+		}
+		if src == dst {
+			return -1, -1, fmt.Errorf("param %s can't be both the --src and the --dst", c.Src)
+		}
+		return dst, src, nil
+	}
+	if _, _, ok := c.pipe(sig, dst); ok {
+		// the input is piped through what the function returns, so none of
+		// its params are.
+		return dst, -1, nil
+	}
+	for x := 0; x < params.Len(); x++ {
+		p := params.At(x)
+		if x == dst || !c.isSrcType(p.Type()) {
+			continue
+		}
+		if src == -1 {
+			src = x
+		}
+		if hasName(p, srcNames) {
+			src = x
+			break
+		}
+	}
+	if src == -1 {
+		// a function that just takes a string, like strings.ToUpper, may
+		// read it from stdin.
+		var cliParams []int
+		for x := 0; x < params.Len(); x++ {
+			if x != dst && !c.isContext(params.At(x).Type()) {
+				cliParams = append(cliParams, x)
 			}
 		}
+		if len(cliParams) == 1 && isString(params.At(cliParams[0]).Type()) {
+			src = cliParams[0]
+		}
 	}
-	if src != -1 {
-		return dst, src, true
+	return dst, src, nil
+}
+
+// streamParam returns the index of the param called name, given by the flag
+// to be a stream, which ok reports whether its type may be.
+func (c *Command) streamParam(params *types.Tuple, name, flag string, ok func(types.Type) bool) (int, error) {
+	for x := 0; x < params.Len(); x++ {
+		p := params.At(x)
+		if paramName(p, x) != name {
+			continue
+		}
+		if !ok(p.Type()) {
+			return -1, fmt.Errorf("param %s of type %s can't be the %s", name, typeString(p.Type()), flag)
+		}
+		return x, nil
+	}
+	return -1, fmt.Errorf("%s.%s has no param %s for %s", c.Package, c.Function, name, flag)
+}
+
+// hasName reports whether p has one of the names.
+func hasName(p *types.Var, names []string) bool {
+	for _, n := range names {
+		if p.Name() == n {
+			return true
+		}
 	}
-	return -1, -1, false
+	return false
+}
+
+func isString(t types.Type) bool {
+	return types.Identical(t, types.Typ[types.String])
 }
 
 func (c *Command) isDstType(t types.Type) bool {
@@ -440,6 +1012,11 @@ func (c *Command) isSrcType(t types.Type) bool {
 	return ok
 }
 
+// isContext reports whether t is context.Context, which the script supplies.
+func (c *Command) isContext(t types.Type) bool {
+	return types.Identical(t, c.contextType)
+}
+
 func isByteArray(t types.Type) bool {
 	arr, ok := t.(*types.Array)
 	if !ok {
@@ -452,6 +1029,12 @@ func (c *Command) isReader(t types.Type) bool {
 	return types.Implements(t, c.ioReader)
 }
 
+// isFile reports whether t is *os.File.
+func (c *Command) isFile(t types.Type) bool {
+	osPkg := c.prog.Package("os")
+	return osPkg != nil && types.Identical(t, types.NewPointer(osPkg.Pkg.Scope().Lookup("File").Type()))
+}
+
 func (c *Command) hasReader(t types.Type) bool {
 	if p, ok := t.(*types.Pointer); ok {
 		t = p.Elem()
@@ -471,7 +1054,7 @@ func (c *Command) hasReader(t types.Type) bool {
 	return false
 }
 
-func (c *Command) readerField(t types.Type) string {
+func (c *Command) readerField(t types.Type) *types.Var {
 	if p, ok := t.(*types.Pointer); ok {
 		t = p.Elem()
 	}
@@ -484,7 +1067,7 @@ func (c *Command) readerField(t types.Type) string {
 	for x := 0; x < s.NumFields(); x++ {
 		f := s.Field(x)
 		if f.Exported() && c.isReader(f.Type()) {
-			return f.Name()
+			return f
 		}
 	}
 	panic(fmt.Sprintf("type %q should have a field that implements io.Reader but does not", t))
@@ -494,6 +1077,8 @@ type retHandler struct {
 	Filter  func(types.Type) bool
 	Imports []string
 	Code    func(types.Type) string
+	// Decls holds the declarations of the types and functions Code uses.
+	Decls []string
 }
 
 var defaultRetHandler = retHandler{
@@ -530,24 +1115,74 @@ if _, err := fmt.Fprintf(os.Stdout, "%x\n", val); err != nil {
 			},
 		},
 		{
-			Filter:  c.isReader,
-			Imports: []string{"fmt", "os", "log", "io"},
+			// a []byte, like json.MarshalIndent's, is written as is, rather
+			// than as a list of numbers.
+			Filter: func(t types.Type) bool {
+				return types.Identical(t, byteSliceType)
+			},
+			Imports: []string{"fmt", "os", "log"},
+			Decls:   []string{stdoutWriterDecl},
 			Code: func(types.Type) string {
 				return `
-	_, err := io.Copy(os.Stdout, val); err != nil {
+	out := &stdoutWriter{}
+	if _, err := out.Write(val); err != nil {
 		log.Fatal(err)
 	}
+	out.endLine()
 `
 			},
 		},
+		{
+			// a reader, like gzip.NewReader's, is a stream we copy to stdout as
+			// is, but not an *os.File, like os.Create's, which may be write
+			// only.
+			Filter: func(t types.Type) bool {
+				return c.isReader(t) && !c.isFile(t)
+			},
+			Imports: []string{"os", "log", "io"},
+			Code: func(t types.Type) string {
+				code := `
+	if _, err := io.Copy(os.Stdout, val); err != nil {
+		log.Fatal(err)
+	}
+`
+				if hasMethod(t, "Close") {
+					code += `
+	if err := val.Close(); err != nil {
+		log.Fatal(err)
+	}
+`
+				}
+				return code
+			},
+		},
 		{
 			Filter:  c.hasReader,
 			Imports: []string{"fmt", "os", "log", "io"},
 			Code: func(t types.Type) string {
+				// the reader may not be set, e.g. the Stdin of an
+				// exec.Cmd from exec.CommandContext, so only copy from
+				// it if it's there, and print the value if it isn't.
+				f := c.readerField(t)
+				var set []string
+				if _, ok := t.(*types.Pointer); ok {
+					set = append(set, "val != nil")
+				}
+				switch f.Type().Underlying().(type) {
+				case *types.Pointer, *types.Interface, *types.Map, *types.Chan, *types.Signature:
+					set = append(set, "val."+f.Name()+" != nil")
+				}
+				if len(set) == 0 {
+					set = append(set, "true")
+				}
 				return fmt.Sprintf(`
-	n, err := io.Copy(os.Stdout, val.%s)
-	if err != nil {
-		log.Fatal(err)
+	var n int64
+	if %s {
+		var err error
+		n, err = io.Copy(os.Stdout, val.%s)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 	if n == 0 {
 		if _, err := fmt.Fprintf(os.Stdout, "%%v\n", val); err != nil {
@@ -555,7 +1190,7 @@ if _, err := fmt.Fprintf(os.Stdout, "%x\n", val); err != nil {
 		}
 	}
 	fmt.Println("")
-`, c.readerField(t))
+`, strings.Join(set, " && "), f.Name())
 			},
 		},
 	}
@@ -571,6 +1206,18 @@ const errCheck = `
 // parseResults ensures that the return value on the signature is one that we
 // can support, and creates the data to output in the template data.
 func (data *templateData) parseResults(results *types.Tuple) error {
+	if data.byteDst {
+		return data.parseCountResults(results)
+	}
+	if data.Pipe != "" {
+		// the result is used by the pipe code.
+		data.Results = "val := "
+		if results.Len() == 2 {
+			data.Results = "val, err := "
+			data.ErrCheck = errCheck
+		}
+		return nil
+	}
 	switch results.Len() {
 	case 0:
 		return nil
@@ -608,9 +1255,42 @@ func (data *templateData) parseResults(results *types.Tuple) error {
 	}
 }
 
+// parseCountResults handles the results of a function with a []byte dst, which
+// may return the number of bytes it wrote to dst, and then an error.  Only the
+// bytes written are written to stdout.
+func (data *templateData) parseCountResults(results *types.Tuple) error {
+	written := "dst"
+	n := results.Len()
+	if n > 0 && types.Identical(results.At(n-1).Type(), errorType) {
+		data.ErrCheck = errCheck
+		n--
+	}
+	switch {
+	case n == 1 && types.Identical(results.At(0).Type().Underlying(), types.Typ[types.Int]):
+		written = "dst[:n]"
+		data.Results = "n := "
+		if data.ErrCheck != "" {
+			data.Results = "n, err := "
+		}
+	case n > 0:
+		return errors.New("can't understand function with a []byte dst that returns values other than a count of bytes and an error")
+	case data.ErrCheck != "":
+		data.Results = "err := "
+	}
+	data.DstToStdout = fmt.Sprintf(`
+	out := &stdoutWriter{}
+	if _, err := out.Write(%s); err != nil {
+		log.Fatal(err)
+	}
+	out.endLine()
+`, written)
+	return nil
+}
+
 func (data *templateData) setReturnType(t types.Type) {
 	h := data.cmd.retHandler(t)
 	data.PrintVal = h.Code(t)
+	data.addConverter(converter{Funcs: h.Decls})
 	data.HasRetVal = true
 	data.Imports["text/template"] = struct{}{}
 	data.Imports["fmt"] = struct{}{}
@@ -626,7 +1306,7 @@ func (data *templateData) setReturnType(t types.Type) {
 func hasError(sig *types.Signature) bool {
 	if len := sig.Results().Len(); len > 0 {
 		// We only care about the last value.
-		return types.Identical(sig.Results().At(len-1).Type().Underlying(), errorType)
+		return types.Identical(sig.Results().At(len-1).Type(), errorType)
 	}
 	return false
 }
@@ -651,14 +1331,21 @@ type srcHandler struct {
 	// Init holds the line that initializes the src variable.
 	Init string
 	// ArgToSrc holds the definition of a function that is put at the bottom of the
-	// file to convert the src CLI arg into the proper format for the function.  It
-	// is expected to contain a %d format directive taking the index of the src arg
-	// from the cli args.
+	// file to convert the src CLI arg (a filename) into the proper format for
+	// the function.
 	ArgToSrc string
 	// StdInToSrc holds the definition of a function that is put at the bottom
 	// of the file to convert data sent to stdin into a format suitable to pass
 	// to the function.
 	StdinToSrc string
+	// Value, if true, means the src CLI arg is the value itself, rather than a
+	// filename, e.g. for strings.
+	Value bool
+	// Desc describes the src CLI arg in the usage message.
+	Desc string
+	// Stream converts the CLI arg for a param of the type that isn't the src,
+	// but is another stream of input, from a file or - for stdin.
+	Stream converter
 }
 
 // have to do it this way since some types won't work in maps.
@@ -671,22 +1358,29 @@ func (c *Command) srcHandler(t types.Type) (srcHandler, bool) {
 	return srcHandler{}, false
 }
 
+// srcHandler returns the handler for the type of the src param, which may be a
+// string if checkSrcDst chose one.
+func (data *templateData) srcHandler(t types.Type) (srcHandler, bool) {
+	if isString(t) {
+		return stringSrc, true
+	}
+	return data.cmd.srcHandler(t)
+}
+
 func (c *Command) setSrcHandlers() {
 	c.srcHandlers = []srcHandler{
 		{
 			Type:    byteSliceType,
 			Imports: []string{"io/ioutil", "log"},
 			Init:    "var src []byte",
+			Desc:    srcFileDesc,
 			ArgToSrc: `
-func argsToSrc(args []string) ([]byte, []string) {
-	srcIdx := %d
-	src, err := ioutil.ReadFile(args[srcIdx])
+func argToSrc(filename string) []byte {
+	src, err := ioutil.ReadFile(filename)
 	if err != nil {
 		log.Fatal(err)
 	}
-	// Take out the src arg.
-	args = append(args[:srcIdx], args[srcIdx+1:]...)
-	return src, args
+	return src
 }
 `,
 			StdinToSrc: `
@@ -697,31 +1391,233 @@ func stdinToSrc() []byte {
 	}
 	return src
 }
-`},
-		{
-			Type:    c.ioReaderType,
-			Imports: []string{"io", "os", "log"},
-			Init:    "var src io.Reader",
-			ArgToSrc: `
-func argsToSrc(args []string) (io.Reader, []string) {
-	srcIdx := %d
+`,
+			Stream: converter{
+				Type:    byteSliceType,
+				Expr:    "argToByteStream(%[1]s, %[2]s)",
+				Imports: []string{"encoding/base64", "encoding/hex", "io", "log", "os", "strconv", "strings"},
+				Funcs:   []string{argToBytesFunc, openStreamFunc, argToByteStreamFunc},
+				Usage:   streamDesc + ", hex: or base64: and the encoded bytes, or a quoted string with -q",
+			}},
+	}
+
+	// streams are opened files, or stdin.
+	ioScope := c.prog.Package("io").Pkg.Scope()
+	for _, name := range []string{"Reader", "ReadCloser"} {
+		c.srcHandlers = append(c.srcHandlers, fileSrcHandler(ioScope.Lookup(name).Type(), "io."+name, "io", false))
+	}
+	// the function's package only uses *os.File or *bufio.Reader if it
+	// imports them.
+	if osPkg := c.prog.Package("os"); osPkg != nil {
+		// stdin is a file too, even if it's a pipe.
+		c.srcHandlers = append(c.srcHandlers, fileSrcHandler(types.NewPointer(osPkg.Pkg.Scope().Lookup("File").Type()), "*os.File", "", false))
+	}
+	// pipes can't seek, so stdin is copied to a temp file for these.
+	for _, name := range []string{"ReadSeeker", "ReadSeekCloser", "ReaderAt"} {
+		c.srcHandlers = append(c.srcHandlers, fileSrcHandler(ioScope.Lookup(name).Type(), "io."+name, "io", true))
+	}
+	// these need a bufio.Reader around the file.
+	for _, name := range []string{"ByteReader", "ByteScanner", "RuneReader", "RuneScanner"} {
+		c.srcHandlers = append(c.srcHandlers, bufioSrcHandler(ioScope.Lookup(name).Type(), "io."+name, "io"))
+	}
+	if bufioPkg := c.prog.Package("bufio"); bufioPkg != nil {
+		c.srcHandlers = append(c.srcHandlers, bufioSrcHandler(types.NewPointer(bufioPkg.Pkg.Scope().Lookup("Reader").Type()), "*bufio.Reader", ""))
+	}
+}
+
+// stringSrc is the handler for a string src, which checkSrcDst only chooses
+// for a function that takes just the one string, like strings.ToUpper, so that
+// it may be read from stdin.  It isn't one of the srcHandlers, since a string
+// isn't a stream.
+var stringSrc = srcHandler{
+	Type:    stringType,
+	Imports: []string{"io", "log", "os", "strings"},
+	Init:    "var src string",
+	Desc:    "read from stdin if omitted",
+	Value:   true,
+	ArgToSrc: `
+func argToSrc(s string) string {
+	return s
+}
+`,
+	StdinToSrc: `
+func stdinToSrc() string {
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := strings.TrimSuffix(string(b), "\n")
+	return strings.TrimSuffix(s, "\r")
+}
+`,
+}
+
+// srcFileDesc describes the src CLI arg for streams in the usage message.
+const srcFileDesc = "a file to read, or stdin if omitted or -"
+
+// streamDesc describes the CLI arg for streams other than the src in the usage
+// message.
+const streamDesc = "a file to read, or - for stdin"
+
+// openStreamFunc is the helper the script uses to open the file for a stream
+// that isn't the src.
+const openStreamFunc = `
+// openStream opens the file to read for a stream arg, or returns stdin for -.
+// If seek is true, stdin is copied to a temp file if it can't seek.
+func openStream(arg string, seek bool) *os.File {
+	if arg != "-" {
+		// closed when the process exits, like the src.
+		f, err := os.Open(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return f
+	}
+	if stdinUsed {
+		log.Fatal("Only one argument may be read from stdin.")
+	}
+	stdinUsed = true
+	if fi, err := os.Stdin.Stat(); !seek || (err == nil && fi.Mode().IsRegular()) {
+		return os.Stdin
+	}
+	f, err := os.CreateTemp("", "gorram")
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Remove(f.Name())
+	if _, err := io.Copy(f, os.Stdin); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		log.Fatal(err)
+	}
+	return f
+}
+`
+
+// argToByteStreamFunc is the helper the script uses to read a []byte stream
+// that isn't the src.
+const argToByteStreamFunc = `
+// argToByteStream returns the contents of the file for a []byte stream arg, or
+// of stdin for -, or the bytes encoded by an arg that starts with hex: or
+// base64:, or, with -q, the bytes of a Go quoted string.
+func argToByteStream(arg, name string) []byte {
+	switch {
+	case strings.HasPrefix(arg, "hex:") || strings.HasPrefix(arg, "base64:"):
+		return argToBytes(arg, name)
+	case os.Getenv("GORRAM_QUOTED") != "" && arg != "" && (arg[0] == '"' || arg[0] == '\x60'):
+		s, err := strconv.Unquote(arg)
+		if err != nil {
+			log.Fatalf("Invalid quoted string %s: %v.", arg, err)
+		}
+		return []byte(s)
+	}
+	b, err := io.ReadAll(openStream(arg, false))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return b
+}
+`
+
+// fileSrcHandler returns the handler for a src of type t, named typeName in the
+// script, that an *os.File can be used for.  If seek is true, stdin is copied
+// to a temp file if it can't seek.
+func fileSrcHandler(t types.Type, typeName, pkg string, seek bool) srcHandler {
+	h := srcHandler{
+		Type:    t,
+		Imports: []string{"os", "log"},
+		Init:    "var src " + typeName,
+		Desc:    srcFileDesc,
+		ArgToSrc: fmt.Sprintf(`
+func argToSrc(filename string) %s {
 	// yes, I know I never close this. It gets closed when the process exits.
 	// It's ugly, but it works and it simplifies the code.  Sorry.
-	src, err := os.Open(args[srcIdx])
+	src, err := os.Open(filename)
 	if err != nil {
 		log.Fatal(err)
 	}
-	// Take out the src arg.
-	args = append(args[:srcIdx], args[srcIdx+1:]...)
-	return src, args
+	return src
 }
-`,
-			StdinToSrc: `
-func stdinToSrc() io.Reader {
+`, typeName),
+		StdinToSrc: fmt.Sprintf(`
+func stdinToSrc() %s {
 	return os.Stdin
 }
-`},
+`, typeName),
+		Stream: converter{
+			Type:    t,
+			Expr:    fmt.Sprintf("openStream(%%[1]s, %t)", seek),
+			Imports: []string{"io", "log", "os"},
+			Funcs:   []string{openStreamFunc},
+			Usage:   streamDesc,
+		},
+	}
+	if pkg != "" {
+		h.Imports = append(h.Imports, pkg)
+	}
+	if seek {
+		h.Imports = append(h.Imports, "io")
+		h.StdinToSrc = fmt.Sprintf(`
+func stdinToSrc() %s {
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode().IsRegular() {
+		return os.Stdin
+	}
+	f, err := os.CreateTemp("", "gorram")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// removing the file while it's open means it's cleaned up when we exit,
+	// at least on unix.
+	os.Remove(f.Name())
+	if _, err := io.Copy(f, os.Stdin); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		log.Fatal(err)
+	}
+	return f
+}
+`, typeName)
+	}
+	return h
+}
+
+// bufioSrcHandler returns the handler for a src of type t, named typeName in
+// the script, that a *bufio.Reader can be used for.
+func bufioSrcHandler(t types.Type, typeName, pkg string) srcHandler {
+	h := srcHandler{
+		Type:    t,
+		Imports: []string{"bufio", "os", "log"},
+		Init:    "var src " + typeName,
+		Desc:    srcFileDesc,
+		ArgToSrc: fmt.Sprintf(`
+func argToSrc(filename string) %s {
+	// closed when the process exits, like other files.
+	f, err := os.Open(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return bufio.NewReader(f)
+}
+`, typeName),
+		StdinToSrc: fmt.Sprintf(`
+func stdinToSrc() %s {
+	return bufio.NewReader(os.Stdin)
+}
+`, typeName),
+		Stream: converter{
+			Type:    t,
+			Expr:    "bufio.NewReader(openStream(%[1]s, false))",
+			Imports: []string{"bufio", "io", "log", "os"},
+			Funcs:   []string{openStreamFunc},
+			Usage:   streamDesc,
+		},
+	}
+	if pkg != "" {
+		h.Imports = append(h.Imports, pkg)
 	}
+	return h
 }
 
 // dstHandler contains the code to handle destination arguments in a function.
@@ -737,6 +1633,13 @@ type dstHandler struct {
 	//  ToStdout contains the code that handles writing the data written to dst
 	//  to stdout.
 	ToStdout string
+	// EndLine contains the code run after ToStdout that adds a newline to the
+	// end of the output if it needs one.  It isn't used for output piped
+	// through the writer the function returns, which is left as is.
+	EndLine string
+	// Decls holds the declarations of the types and functions Init and
+	// ToStdout use.
+	Decls []string
 }
 
 func (c *Command) dstHandler(t types.Type) (dstHandler, bool) {
@@ -745,33 +1648,231 @@ func (c *Command) dstHandler(t types.Type) (dstHandler, bool) {
 			return h, true
 		}
 	}
+	if types.Identical(t, byteSliceType) {
+		// the function writes to a []byte we have to allocate, which we can
+		// only do if we know how big it needs to be.
+		size, ok := c.dstSize()
+		if !ok {
+			return dstHandler{}, false
+		}
+		return dstHandler{
+			Type:    t,
+			Imports: []string{"fmt", "log", "os"},
+			Init:    fmt.Sprintf("dst := make([]byte, %s)", size),
+			Decls:   []string{stdoutWriterDecl},
+			// ToStdout depends on the function's results.
+		}, true
+	}
 	return dstHandler{}, false
 }
 
+// dstSizers are the functions that give the size of the []byte dst that a
+// function whose name starts with the key needs, e.g. hex.EncodedLen for
+// hex.Encode.
+var dstSizers = map[string][]string{
+	"Encode": {"EncodedLen", "MaxEncodedLen"},
+	"Decode": {"DecodedLen", "MaxDecodedLen"},
+}
+
+// dstSize returns the expression for the size of the function's []byte dst,
+// which calls the sizer in the function's package (or on its global variable)
+// with the length of the src.
+func (c *Command) dstSize() (string, bool) {
+	for prefix, names := range dstSizers {
+		if !strings.HasPrefix(c.Function, prefix) {
+			continue
+		}
+		for _, name := range names {
+			sibling := *c
+			sibling.Function = name
+			f, err := sibling.getFunc()
+			if err != nil {
+				continue
+			}
+			sig := f.Type().(*types.Signature)
+			if sig.Params().Len() != 1 || sig.Results().Len() != 1 ||
+				!types.Identical(sig.Params().At(0).Type(), types.Typ[types.Int]) ||
+				!types.Identical(sig.Results().At(0).Type(), types.Typ[types.Int]) {
+				continue
+			}
+			fn := c.pkg().Name() + "." + name
+			if c.GlobalVar != "" {
+				fn = c.pkg().Name() + "." + c.GlobalVar + "." + name
+			}
+			return fn + "(len(src))", true
+		}
+	}
+	return "", false
+}
+
 func (c *Command) setDstHandlers() {
 	c.dstHandlers = []dstHandler{
 		{
 			Type:    c.pBufferType,
-			Imports: []string{"bytes", "io", "fmt"},
+			Imports: []string{"bytes", "io", "fmt", "log", "os"},
 			Init:    "dst := &bytes.Buffer{}",
+			Decls:   []string{stdoutWriterDecl},
 			ToStdout: `
-	if _, err := io.Copy(os.Stdout, dst); err != nil {
+	out := &stdoutWriter{}
+	if _, err := io.Copy(out, dst); err != nil {
 		log.Fatal(err)
 	}
-	// ensure we end with at least one line return.
-	fmt.Println("")
+`,
+			EndLine: `
+	out.endLine()
 `},
-		{
-			Type:    c.ioWriterType,
-			Imports: []string{"os", "fmt"},
-			Init:    "dst := os.Stdout",
+		stdoutDstHandler(c.ioWriterType, "io"),
+	}
+	ioScope := c.prog.Package("io").Pkg.Scope()
+	for _, name := range []string{"StringWriter", "WriteCloser"} {
+		c.dstHandlers = append(c.dstHandlers, stdoutDstHandler(ioScope.Lookup(name).Type(), "io"))
+	}
+	// the function's package only uses these types if it imports their
+	// packages.
+	if stringsPkg := c.prog.Package("strings"); stringsPkg != nil {
+		c.dstHandlers = append(c.dstHandlers, dstHandler{
+			Type:    types.NewPointer(stringsPkg.Pkg.Scope().Lookup("Builder").Type()),
+			Imports: []string{"strings", "fmt", "log", "os"},
+			Init:    "dst := &strings.Builder{}",
+			Decls:   []string{stdoutWriterDecl},
 			ToStdout: `
-	// ensure we end with at least one line return
-	fmt.Println("")
-			`,
-		},
+	out := &stdoutWriter{}
+	if _, err := out.WriteString(dst.String()); err != nil {
+		log.Fatal(err)
+	}
+`,
+			EndLine: `
+	out.endLine()
+`})
+	}
+	if bufioPkg := c.prog.Package("bufio"); bufioPkg != nil {
+		c.dstHandlers = append(c.dstHandlers, dstHandler{
+			Type:    types.NewPointer(bufioPkg.Pkg.Scope().Lookup("Writer").Type()),
+			Imports: []string{"bufio", "fmt", "log", "os"},
+			Init: `out := &stdoutWriter{}
+	dst := bufio.NewWriter(out)`,
+			Decls: []string{stdoutWriterDecl},
+			ToStdout: `
+	if err := dst.Flush(); err != nil {
+		log.Fatal(err)
+	}
+`,
+			EndLine: `
+	out.endLine()
+`})
+	}
+	if osPkg := c.prog.Package("os"); osPkg != nil {
+		// the function gets a pipe, rather than stdout itself, so we can
+		// tell how its output ends.
+		c.dstHandlers = append(c.dstHandlers, dstHandler{
+			Type:    types.NewPointer(osPkg.Pkg.Scope().Lookup("File").Type()),
+			Imports: []string{"io", "fmt", "log", "os"},
+			Init: `out := &stdoutWriter{}
+	dst, wait := stdoutPipe(out)`,
+			Decls: []string{stdoutWriterDecl, stdoutPipeDecl},
+			ToStdout: `
+	wait()
+`,
+			EndLine: `
+	out.endLine()
+`})
+	}
+}
+
+// stdoutDstHandler returns the handler for a dst of type t, an interface from
+// the package pkg that the script's stdoutWriter implements.  It is closed
+// after the function returns if it is an io.WriteCloser.
+func stdoutDstHandler(t types.Type, pkg string) dstHandler {
+	h := dstHandler{
+		Type:    t,
+		Imports: []string{pkg, "fmt", "os"},
+		Init:    "dst := &stdoutWriter{}",
+		Decls:   []string{stdoutWriterDecl},
+		EndLine: `
+	dst.endLine()
+`,
+	}
+	if iface, ok := t.Underlying().(*types.Interface); ok {
+		for i := 0; i < iface.NumMethods(); i++ {
+			if iface.Method(i).Name() == "Close" {
+				h.Imports = append(h.Imports, "log")
+				h.ToStdout = `
+	if err := dst.Close(); err != nil {
+		log.Fatal(err)
+	}
+`
+			}
+		}
+	}
+	return h
+}
+
+// stdoutWriterDecl is the writer the script writes a dst's output to stdout
+// with.
+const stdoutWriterDecl = `
+// stdoutWriter writes to stdout, keeping track of how the output ends, so
+// that we only add a newline to the end if it needs one.
+type stdoutWriter struct {
+	wrote  bool
+	last   byte
+	closed bool
+}
+
+func (w *stdoutWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	n, err := os.Stdout.Write(p)
+	if n > 0 {
+		w.wrote = true
+		w.last = p[n-1]
+	}
+	return n, err
+}
+
+func (w *stdoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Close stops further writes, but leaves stdout open so we can end the line.
+func (w *stdoutWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+// endLine writes a newline if the output doesn't end with one.
+func (w *stdoutWriter) endLine() {
+	if w.wrote && w.last != '\n' {
+		fmt.Println()
 	}
 }
+`
+
+// stdoutPipeDecl creates the file the script gives a function as its dst when
+// it must be an *os.File.
+const stdoutPipeDecl = `
+// stdoutPipe returns a file whose output is copied to stdout through out, and
+// a function that waits for the copying to finish once the file has been
+// written.
+func stdoutPipe(out *stdoutWriter) (*os.File, func()) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		log.Fatal(err)
+	}
+	copied := make(chan struct{})
+	go func() {
+		if _, err := io.Copy(out, r); err != nil {
+			log.Fatal(err)
+		}
+		close(copied)
+	}()
+	return w, func() {
+		// the function may have closed it already.
+		w.Close()
+		<-copied
+	}
+}
+`
 
 // converter is a type that holds information about argument conversions from
 // CLI strings to function arguments of various types.  If a function takes an
@@ -780,24 +1881,185 @@ func (c *Command) setDstHandlers() {
 type converter struct {
 	// Type is the types.Type this converter converts.
 	Type types.Type
-	// Assign is a format string that is used to make the conversion between CLI
-	// arg x and function arg y in the body of the main function.  It should
-	// take the cli arg index and the function arg index as %d format values.
-	// Ideally, it is a single line of code, which may call a helper function.
-	// If it calls a helper function, that function must be listed in Func.
-	Assign string
-	// Imports is the list of imports that Func uses, so we can make sure
+	// Expr is a format string for the expression that converts a CLI arg into
+	// the function arg.  It is passed the expression holding the CLI string
+	// (e.g. args[0]) and the expression holding the parameter name (for error
+	// messages), so it should use explicit argument indexes (%[1]s and %[2]s)
+	// to pick the ones it needs.  Ideally, it is a single function call. If it
+	// calls helper functions, those functions must be listed in Funcs.
+	Expr string
+	// Imports is the list of imports that Funcs use, so we can make sure
 	// they're added to the list of imports.
 	Imports []string
-	// Func is the declaration of the conversion function between a string (the
-	// CLI arg) and a given type.  It must only return a single value of the
-	// appropriate type.  Errors should be handled with log.Fatal(err).  It
-	// should be named argTo<type> to avoid collision with other conversion
-	// function.
-	Func string
+	// Funcs holds the declarations of the conversion functions between a
+	// string (the CLI arg) and a given type.  They must only return a single
+	// value of the appropriate type.  Errors should be handled with log.Fatal,
+	// and should mention the name of the parameter and its type.  They should
+	// be named argTo<type> to avoid collision with other conversion functions,
+	// since the same function may be needed by more than one converter.
+	Funcs []string
+	// Usage describes the values the converter accepts, for the script's usage
+	// message.  It may be empty if the type says it all.
+	Usage string
+	// Multi, if true, means Expr converts a []string holding all the values
+	// given for the param, rather than a single string.
+	Multi bool
+	// NoSplit, if true, means a Multi param given as a positional arg is passed
+	// to Expr whole, rather than split on commas, because Expr knows how to
+	// split it up itself.
+	NoSplit bool
+	// Struct, if true, means the param is a struct (or pointer to one), and so
+	// is optional.  Its Expr gets all the values given for it, with the values
+	// for its fields given as --Field=value.
+	Struct bool
+	// Fields describes the fields of a struct that may be set with
+	// --Field=value flags.
+	Fields []usageArg
+	// Parse, for basic types other than string, is the strconv call that
+	// parses the CLI arg s, e.g. strconv.ParseInt(s, 0, 64), so that named
+	// types with the type as their underlying type can use it too.
+	Parse string
 }
 
 func (c *Command) argConverter(t types.Type) (converter, bool) {
+	t = types.Unalias(t)
+	if _, ok := t.(*types.TypeParam); ok {
+		// only an expression will do until the function is instantiated.
+		return converter{}, false
+	}
+	if conv, ok := c.basicConverter(t); ok {
+		// numbers may also be given as the name of one of the constants in
+		// the function's package, e.g. http.StatusText StatusNotFound.  Not
+		// runes, since a letter is the character, not a constant's name.
+		if b, ok := t.(*types.Basic); ok && b.Info()&types.IsNumeric != 0 && b.Name() != "rune" {
+			if consts, suggested := c.constsOf(c.pkg(), t); len(consts) > 0 {
+				fn := "argTo" + title(c.pkg().Name()) + title(b.Name())
+				return c.constConverter(fn, t, consts, suggested, conv), true
+			}
+		}
+		return conv, true
+	}
+	conv, ok := c.parserConverter(t)
+	if !ok {
+		conv, ok = c.structConverter(t)
+	}
+	_, isPtr := t.(*types.Pointer)
+	iface, isIface := t.Underlying().(*types.Interface)
+	isIface = isIface && !iface.Empty()
+	if ok {
+		// pointers and interfaces may also be given by name, e.g.
+		// base64.URLEncoding or http.DefaultClient.
+		if isPtr || isIface {
+			return c.globalConverter(t, &conv)
+		}
+		return conv, true
+	}
+	// e.g. *crc32.Table, which can only be given by name, as
+	// crc32.IEEETable.
+	if isPtr || isIface {
+		return c.globalConverter(t, nil)
+	}
+	// funcs may be given as the name of a function, e.g. unicode.ToUpper.
+	if _, ok := t.Underlying().(*types.Signature); ok {
+		return c.globalConverter(t, nil)
+	}
+	if named, ok := t.(*types.Named); ok {
+		return c.namedConverter(named)
+	}
+	if slice, ok := t.(*types.Slice); ok {
+		return c.sliceConverter(slice)
+	}
+	if m, ok := t.(*types.Map); ok {
+		return c.mapConverter(m)
+	}
+	if isByteArray(t) {
+		return byteArrayConverter(t.(*types.Array)), true
+	}
+	return converter{}, false
+}
+
+// argToBytesFunc is the helper the script uses to convert an arg to bytes.
+const argToBytesFunc = `
+// argToBytes returns the bytes of s, or, if s starts with hex: or base64:, the
+// bytes it encodes.
+func argToBytes(s, name string) []byte {
+	var b []byte
+	var err error
+	switch {
+	case strings.HasPrefix(s, "hex:"):
+		b, err = hex.DecodeString(s[len("hex:"):])
+	case strings.HasPrefix(s, "base64:"):
+		// accept padded or unpadded, standard or URL encodings.
+		enc := strings.TrimRight(s[len("base64:"):], "=")
+		if b, err = base64.RawStdEncoding.DecodeString(enc); err != nil {
+			b, err = base64.RawURLEncoding.DecodeString(enc)
+		}
+	default:
+		return []byte(s)
+	}
+	if err != nil {
+		log.Fatalf("invalid value %q for %s ([]byte): %v", s, name, err)
+	}
+	return b
+}
+`
+
+// byteArrayConverter handles byte arrays, such as a sha256 sum, which may be
+// given like a []byte, but must be exactly the length of the array.
+func byteArrayConverter(t *types.Array) converter {
+	fn := convFuncName(t)
+	return converter{
+		Type:    t,
+		Expr:    fn + "(%[1]s, %[2]s)",
+		Imports: []string{"encoding/base64", "encoding/hex", "log", "strings"},
+		Funcs: []string{argToBytesFunc, fmt.Sprintf(`
+func %[1]s(s, name string) (v %[2]s) {
+	b := argToBytes(s, name)
+	if len(b) != len(v) {
+		log.Fatalf("invalid value %%q for %%s (%[2]s): got %%d bytes, expected %%d", s, name, len(b), len(v))
+	}
+	copy(v[:], b)
+	return v
+}
+`, fn, typeString(t))},
+		Usage: fmt.Sprintf("%d bytes, or hex: or base64: and the encoded bytes", t.Len()),
+	}
+}
+
+// sliceConverter handles slices of any type we can convert.  Each value given
+// for the param is converted to an element of the slice.
+func (c *Command) sliceConverter(t *types.Slice) (converter, bool) {
+	elem, ok := c.argConverter(t.Elem())
+	if !ok || elem.Multi {
+		return converter{}, false
+	}
+	fn := convFuncName(t)
+	return converter{
+		Type:    t,
+		Expr:    fn + "(%[1]s, %[2]s)",
+		Imports: elem.Imports,
+		Funcs: append([]string{fmt.Sprintf(`
+func %[1]s(vals []string, name string) %[2]s {
+	v := make(%[2]s, len(vals))
+	for i, s := range vals {
+		v[i] = %[3]s
+	}
+	return v
+}
+`, fn, c.scriptType(t), fmt.Sprintf(elem.Expr, "s", "name"))}, elem.Funcs...),
+		Usage: elem.Usage,
+		Multi: true,
+	}, true
+}
+
+// basicConverter returns the converter from the list of converters for exactly
+// the given type.
+func (c *Command) basicConverter(t types.Type) (converter, bool) {
+	// rune is an alias of int32, but go/types keeps its name, and a rune is
+	// given as a character, e.g. unicode.IsUpper A.
+	if b, ok := t.(*types.Basic); ok && b.Name() == "rune" {
+		return runeConverter, true
+	}
 	for _, c := range c.argConverters {
 		if types.Identical(t, c.Type) {
 			return c, true
@@ -806,94 +2068,503 @@ func (c *Command) argConverter(t types.Type) (converter, bool) {
 	return converter{}, false
 }
 
+// namedConverter handles named types whose underlying type is a basic type,
+// such as time.Month or os.FileMode, or a slice or map, such as http.Header.
+// The CLI arg is converted using the converter for the underlying type, and
+// then converted to the named type.  Basic types are parsed by a function of
+// their own, so that errors name the named type.
+func (c *Command) namedConverter(t *types.Named) (converter, bool) {
+	obj := t.Obj()
+	if obj.Pkg() == nil || !obj.Exported() {
+		// we can't refer to the type from the script.
+		return converter{}, false
+	}
+	var base converter
+	var ok bool
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		base, ok = c.basicConverter(u)
+	case *types.Slice:
+		base, ok = c.argConverter(u)
+	case *types.Map:
+		base, ok = c.argConverter(u)
+	case *types.Array:
+		base, ok = c.argConverter(u)
+	}
+	if !ok {
+		return converter{}, false
+	}
+	base = converter{
+		Type:    t,
+		Expr:    c.qualifiedName(obj) + "(" + base.Expr + ")",
+		Imports: append([]string{obj.Pkg().Path()}, base.Imports...),
+		Funcs:   base.Funcs,
+		Usage:   base.Usage,
+		Multi:   base.Multi,
+		NoSplit: base.NoSplit,
+		Parse:   base.Parse,
+	}
+	if base.Parse != "" {
+		fn := "parse" + typeIdent(t)
+		base.Expr = fn + "(%[1]s, %[2]s)"
+		base.Funcs = []string{parseFunc(fn, c.scriptType(t), typeString(t), base.Parse)}
+	}
+	// enum-like types can also be specified by the name of their constants,
+	// e.g. time.Month can be given as March.
+	if consts, suggested := c.constsOf(obj.Pkg(), t); len(consts) > 0 && !base.Multi {
+		return c.constConverter(convFuncName(t), t, consts, suggested, base), true
+	}
+	return base, true
+}
+
+// exportedNamed returns the named type for t or *t if it is an exported type
+// that can be referred to from the script.
+func exportedNamed(t types.Type) (*types.Named, bool) {
+	if p, ok := t.(*types.Pointer); ok {
+		t = types.Unalias(p.Elem())
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return nil, false
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil || !obj.Exported() || named.TypeArgs().Len() > 0 {
+		return nil, false
+	}
+	return named, true
+}
+
+// convFuncName returns the name of the conversion function for the given type,
+// e.g. argToTimeMonth, argToPtrBigInt or argToStringSlice.
+func convFuncName(t types.Type) string {
+	return "argTo" + typeIdent(t)
+}
+
+// typeIdent returns a version of the type's name that can be used as part of
+// an identifier.
+func typeIdent(t types.Type) string {
+	switch t := types.Unalias(t).(type) {
+	case *types.Basic:
+		return title(t.Name())
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() == nil {
+			// e.g. error
+			return title(obj.Name())
+		}
+		return title(obj.Pkg().Name()) + obj.Name()
+	case *types.Pointer:
+		return "Ptr" + typeIdent(t.Elem())
+	case *types.Slice:
+		return typeIdent(t.Elem()) + "Slice"
+	case *types.Array:
+		return fmt.Sprintf("%sArray%d", typeIdent(t.Elem()), t.Len())
+	case *types.Map:
+		return typeIdent(t.Key()) + "To" + typeIdent(t.Elem()) + "Map"
+	case *types.Interface:
+		if t.Empty() {
+			return "Any"
+		}
+	case *types.Signature:
+		id := "Func"
+		for i := 0; i < t.Params().Len(); i++ {
+			id += typeIdent(t.Params().At(i).Type())
+		}
+		if t.Results().Len() > 0 {
+			id += "To"
+			for i := 0; i < t.Results().Len(); i++ {
+				id += typeIdent(t.Results().At(i).Type())
+			}
+		}
+		return id
+	}
+	return "Type"
+}
+
+// argToAnyOrStdinFunc is the helper the script uses for an interface{} param
+// that may be decoded from stdin.
+const argToAnyOrStdinFunc = `
+// argToAnyOrStdin converts the value given for an interface{} param, or if
+// there isn't one, decodes it from stdin in the format given with --from.
+func argToAnyOrStdin(vals []string, name string) interface{} {
+	if len(vals) > 0 {
+		return argToAny(vals[0], name)
+	}
+	switch from := os.Getenv("GORRAM_FROM"); from {
+	case "json":
+		var v interface{}
+		d := json.NewDecoder(os.Stdin)
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			log.Fatalf("invalid JSON on stdin for %s: %v", name, err)
+		}
+		return v
+	default:
+		log.Fatalf("unknown format %q for --from, expected json", from)
+	}
+	return nil
+}
+`
+
+// argToPairsFunc is the helper the script uses to split the values given for a
+// map param into keys and values.
+const argToPairsFunc = `
+// argToPairs splits the values given for a map param into keys and the values
+// given for each key, in the order they were first seen.  Each value may be a
+// comma separated list of key=value pairs, or a JSON object.
+func argToPairs(vals []string, name string) ([]string, [][]string) {
+	var keys []string
+	values := map[string][]string{}
+	add := func(k, v string) {
+		if _, ok := values[k]; !ok {
+			keys = append(keys, k)
+		}
+		values[k] = append(values[k], v)
+	}
+	for _, val := range vals {
+		if strings.HasPrefix(strings.TrimSpace(val), "{") {
+			var obj map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(val), &obj); err != nil {
+				log.Fatalf("invalid value %q for %s: %v", val, name, err)
+			}
+			objKeys := make([]string, 0, len(obj))
+			for k := range obj {
+				objKeys = append(objKeys, k)
+			}
+			sort.Strings(objKeys)
+			for _, k := range objKeys {
+				// arrays are multiple values for the key.
+				var items []json.RawMessage
+				if err := json.Unmarshal(obj[k], &items); err != nil {
+					items = []json.RawMessage{obj[k]}
+				}
+				for _, item := range items {
+					// strings need to be unquoted, anything else we can just
+					// use as is.
+					var s string
+					if err := json.Unmarshal(item, &s); err != nil {
+						s = string(item)
+					}
+					add(k, s)
+				}
+			}
+			continue
+		}
+		for _, pair := range strings.Split(val, ",") {
+			if pair == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				log.Fatalf("invalid value %q for %s: expected key=value", pair, name)
+			}
+			add(k, v)
+		}
+	}
+	pairs := make([][]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = values[k]
+	}
+	return keys, pairs
+}
+`
+
+// mapConverter handles maps whose key and value types we can convert.  The
+// values given for the param may be key=value pairs or JSON objects.  If the
+// map's values are lists, each value given for a key is added to the list,
+// otherwise the last value given wins.
+func (c *Command) mapConverter(t *types.Map) (converter, bool) {
+	key, ok := c.argConverter(t.Key())
+	if !ok || key.Multi {
+		return converter{}, false
+	}
+	val, ok := c.argConverter(t.Elem())
+	if !ok || val.NoSplit {
+		return converter{}, false
+	}
+	fn := convFuncName(t)
+	vals := "values[i][len(values[i])-1]"
+	if val.Multi {
+		vals = "values[i]"
+	}
+	funcs := append([]string{fmt.Sprintf(`
+func %[1]s(vals []string, name string) %[2]s {
+	keys, values := argToPairs(vals, name)
+	m := make(%[2]s, len(keys))
+	for i, k := range keys {
+		m[%[3]s] = %[4]s
+	}
+	return m
+}
+`, fn, c.scriptType(t), fmt.Sprintf(key.Expr, "k", "name"), fmt.Sprintf(val.Expr, vals, "name")), argToPairsFunc}, key.Funcs...)
+	return converter{
+		Type:    t,
+		Expr:    fn + "(%[1]s, %[2]s)",
+		Imports: append(append([]string{"encoding/json", "strings", "sort", "log"}, key.Imports...), val.Imports...),
+		Funcs:   append(funcs, val.Funcs...),
+		Usage:   "key=value pairs separated by commas, or a JSON object",
+		Multi:   true,
+		NoSplit: true,
+	}, true
+}
+
+// qualifiedName returns the name of the package level object as it would be
+// referred to from the script, e.g. time.Month.
+func (c *Command) qualifiedName(obj types.Object) string {
+	return c.importName(obj.Pkg()) + "." + obj.Name()
+}
+
+// scriptType returns the type as it would be written in the script, e.g.
+// *big.Int, or *html_template.Template for a package imported by an alias.
+func (c *Command) scriptType(t types.Type) string {
+	return types.TypeString(t, c.importName)
+}
+
+// importName returns the name the script refers to pkg by.  The packages that
+// expressions may use (see loadedPkgs) are imported by their own names, and
+// any other package by an alias made from its path, e.g. html_template, since
+// its name is taken by another package the script imports.
+func (c *Command) importName(pkg *types.Package) string {
+	if c.byName == nil {
+		c.byName = map[string]bool{}
+		for _, p := range c.loadedPkgs() {
+			c.byName[p.Path()] = true
+		}
+		for _, path := range scriptImports {
+			c.byName[path] = true
+		}
+	}
+	if c.byName[pkg.Path()] {
+		return pkg.Name()
+	}
+	alias := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, pkg.Path())
+	if c.aliases == nil {
+		c.aliases = map[string]string{}
+	}
+	c.aliases[pkg.Path()] = alias
+	return alias
+}
+
+// typeString returns the type as it's shown to the user, e.g. *big.Int.
+func typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string { return p.Name() })
+}
+
 func (c *Command) setArgConverters() {
 	c.argConverters = []converter{
 		{
 			// string is a special flower because it doesn't need a converter, but we
 			// keep an empty converter here so that we don't need to special case it
 			// elsewhere.
-			Type:   types.Typ[types.String],
-			Assign: "arg%d := args[%d]",
+			Type: types.Typ[types.String],
+			Expr: "%[1]s",
 		},
 		{
-			Type:    types.Typ[types.Int],
-			Assign:  "arg%d := argToInt(args[%d])",
-			Imports: []string{"strconv", "log"},
-			Func: `
-func argToInt(s string) int {
-	i, err := strconv.ParseInt(s, 0, 0)
-	if err != nil {
-		log.Fatal(err)
+			// []byte args that aren't a src are just the bytes of the arg, not a
+			// list of numbers.
+			Type:    byteSliceType,
+			Expr:    "argToBytes(%[1]s, %[2]s)",
+			Imports: []string{"encoding/base64", "encoding/hex", "log", "strings"},
+			Funcs:   []string{argToBytesFunc},
+			Usage:   "the bytes of the arg, or hex: or base64: and the encoded bytes",
+		},
+		parseConverter(types.Bool, "strconv.ParseBool(s)"),
+		intConverter(types.Int, 0),
+		intConverter(types.Int8, 8),
+		intConverter(types.Int16, 16),
+		intConverter(types.Int32, 32),
+		intConverter(types.Int64, 64),
+		uintConverter(types.Uint, 0),
+		uintConverter(types.Uint8, 8),
+		uintConverter(types.Uint16, 16),
+		uintConverter(types.Uint32, 32),
+		uintConverter(types.Uint64, 64),
+		uintConverter(types.Uintptr, 0),
+		floatConverter(types.Float32, 32),
+		floatConverter(types.Float64, 64),
+		complexConverter(types.Complex64, 64),
+		complexConverter(types.Complex128, 128),
+		{
+			Type:    anyType,
+			Expr:    "argToAny(%[1]s, %[2]s)",
+			Imports: []string{"encoding/json", "strconv", "strings", "log"},
+			Usage:   "a number, bool, quoted string, JSON object or array, nil, or any other string",
+			Funcs: []string{`
+// argToAny infers the type of an interface{} arg from what it looks like.
+func argToAny(s, name string) interface{} {
+	switch {
+	case s == "":
+		return s
+	case s == "nil":
+		return nil
+	case s == "true" || s == "false":
+		return s == "true"
+	case s[0] == '"':
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			log.Fatalf("invalid value %q for %s: bad quoted string", s, name)
+		}
+		return v
+	case s[0] == '{' || s[0] == '[':
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			log.Fatalf("invalid value %q for %s: %v", s, name, err)
+		}
+		return v
+	case strings.ContainsRune("+-.0123456789", rune(s[0])):
+		// numbers are decimal, even with leading zeros, unless they start
+		// with 0x, 0o or 0b.
+		base := 10
+		if d := strings.TrimLeft(s, "+-"); len(d) > 2 && d[0] == '0' && strings.ContainsRune("xXoObB", rune(d[1])) {
+			base = 0
+		}
+		if n, err := strconv.ParseInt(s, base, 64); err == nil {
+			if n == int64(int(n)) {
+				return int(n)
+			}
+			return n
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
 	}
-	return int(i)
+	return s
 }
-`},
+`}},
 		{
-			Type:    types.Typ[types.Uint],
-			Assign:  "arg%d := argToUint(args[%d])",
-			Imports: []string{"strconv", "log"},
-			Func: `
-func argToUint(s string) int {
-	u, err := strconv.ParseUint(s, 0, 0)
+			Type:    c.durationType,
+			Expr:    "argToDuration(%[1]s, %[2]s)",
+			Imports: []string{"time", "log"},
+			Usage:   "a duration like 1h30m or 250ms",
+			Funcs: []string{`
+func argToDuration(s, name string) time.Duration {
+	d, err := time.ParseDuration(s)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("invalid value %q for %s (time.Duration): expected a duration like 1h30m or 250ms", s, name)
 	}
-	return uint(u)
+	return d
 }
-`},
+`}},
 		{
-			Type:    types.Typ[types.Float64],
-			Assign:  "arg%d := argToFloat64(args[%d])",
-			Imports: []string{"strconv", "log"},
-			Func: `
-func argToFloat64(s string) float64 {
-	f, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		log.Fatal(err)
+			Type:    c.timeType,
+			Expr:    "argToTime(%[1]s, %[2]s)",
+			Imports: []string{"time", "log", "strconv"},
+			Usage:   "RFC3339, a date like 2006-01-02, Unix seconds or milliseconds, now, or an offset from now like -2h",
+			Funcs: []string{`
+func argToTime(s, name string) time.Time {
+	if s == "now" {
+		return time.Now()
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		// seconds this big would be thousands of years away, so it must be
+		// milliseconds.
+		if n > 1e11 || n < -1e11 {
+			return time.UnixMilli(n)
+		}
+		return time.Unix(n, 0)
 	}
-	return f
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(d)
+	}
+	log.Fatalf("invalid value %q for %s (time.Time): expected RFC3339, a date like 2006-01-02, Unix seconds or milliseconds, now, or an offset from now like -2h", s, name)
+	return time.Time{}
 }
-`},
-		{
-			Type:    types.Typ[types.Bool],
-			Assign:  "arg%d := argToBool(args[%d])",
-			Imports: []string{"strconv", "log"},
-			Func: `
-func argToBool(s string) bool {
-	b, err := strconv.ParseBool(s)
-	if err != nil {
-		log.Fatal(err)
+`}},
 	}
-	return b
 }
-`},
-		{
-			Type:    types.Typ[types.Int64],
-			Assign:  "arg%d := argToInt64(args[%d])",
-			Imports: []string{"strconv", "log"},
-			Func: `
-func argToInt64(s string) int64 {
-	i, err := strconv.ParseInt(s, 0, 64)
-	if err != nil {
-		log.Fatal(err)
+
+// parseConverter creates a converter for a basic type other than string, where
+// the conversion function calls the given strconv parse function.  The parse
+// function's bitSize argument ensures that values which don't fit in the type
+// are rejected rather than silently truncated.  Note that byte is an alias of
+// uint8, so it's covered by that converter.
+func parseConverter(kind types.BasicKind, parse string) converter {
+	name := types.Typ[kind].Name()
+	fn := "argTo" + title(name)
+	return converter{
+		Type:    types.Typ[kind],
+		Expr:    fn + "(%[1]s, %[2]s)",
+		Imports: []string{"strconv", "log"},
+		Funcs:   []string{parseFunc(fn, name, name, parse)},
+		Parse:   parse,
 	}
-	return i
 }
-`},
-		{
-			Type:    types.Typ[types.Uint64],
-			Assign:  "arg%d := argToUint64(args[%d])",
-			Imports: []string{"strconv", "log"},
-			Func: `
-func argToUint64(s string) uint64 {
-	u, err := strconv.ParseUint(s, 0, 64)
+
+// runeConverter converts a single character to the rune it is.  A longer arg
+// is parsed as the rune's code point, e.g. 0x41 for A.
+var runeConverter = converter{
+	Type:    types.Universe.Lookup("rune").Type(),
+	Expr:    "argToRune(%[1]s, %[2]s)",
+	Imports: []string{"strconv", "log", "unicode/utf8"},
+	Funcs:   []string{argToRuneFunc},
+	Usage:   "a single character, or its code point, e.g. 0x41",
+}
+
+const argToRuneFunc = `
+func argToRune(s, name string) rune {
+	if r, size := utf8.DecodeRuneInString(s); size == len(s) && (r != utf8.RuneError || size > 1) {
+		return r
+	}
+	if utf8.RuneCountInString(s) < 2 {
+		log.Fatalf("invalid value %q for %s (rune): expected a single character", s, name)
+	}
+	v, err := strconv.ParseInt(s, 0, 32)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("invalid value %q for %s (rune): expected a single character or a code point", s, name)
+	}
+	if !utf8.ValidRune(rune(v)) {
+		log.Fatalf("invalid value %q for %s (rune): %#x is not a valid code point", s, name, v)
 	}
-	return u
+	return rune(v)
 }
-`},
+`
+
+// parseFunc returns the declaration of the conversion function fn, which
+// converts the CLI arg to typeName with the strconv call parse, and calls the
+// type desc in errors.
+func parseFunc(fn, typeName, desc, parse string) string {
+	return fmt.Sprintf(`
+func %[1]s(s, name string) %[2]s {
+	v, err := %[4]s
+	if err != nil {
+		log.Fatalf("invalid value %%q for %%s (%[3]s): %%v", s, name, err.(*strconv.NumError).Err)
 	}
+	return %[2]s(v)
+}
+`, fn, typeName, desc, parse)
+}
+
+// title returns s with the first letter in upper case, for making function
+// names.
+func title(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func intConverter(kind types.BasicKind, bits int) converter {
+	return parseConverter(kind, fmt.Sprintf("strconv.ParseInt(s, 0, %d)", bits))
+}
+
+func uintConverter(kind types.BasicKind, bits int) converter {
+	return parseConverter(kind, fmt.Sprintf("strconv.ParseUint(s, 0, %d)", bits))
+}
+
+func floatConverter(kind types.BasicKind, bits int) converter {
+	return parseConverter(kind, fmt.Sprintf("strconv.ParseFloat(s, %d)", bits))
+}
+
+func complexConverter(kind types.BasicKind, bits int) converter {
+	return parseConverter(kind, fmt.Sprintf("strconv.ParseComplex(s, %d)", bits))
 }
 
 func (c *Command) dir() string {
@@ -905,9 +2576,42 @@ func (c *Command) script() string {
 	if c.GlobalVar != "" {
 		name = c.GlobalVar + "." + c.Function
 	}
+	// expressions are compiled into the script, so each set of them needs
+	// its own.
+	if key := c.exprKey(); key != "" {
+		name += "-" + key
+	}
+	// so do the type args for generic functions.
+	if key := c.typeArgsKey(); key != "" {
+		name += "-" + key
+	}
+	// and the params chosen with --src and --dst.
+	if key := c.streamKey(); key != "" {
+		name += "-" + key
+	}
+	// and the parsers chosen with -p, so they're checked against the params
+	// when the script is generated, rather than ignored by a cached one.
+	if key := c.parsersKey(); key != "" {
+		name += "-" + key
+	}
+	// and the packages the args name, since they're loaded for their globals.
+	if key := c.argPkgsKey(); key != "" {
+		name += "-" + key
+	}
 	return filepath.Join(c.dir(), name+".go")
 }
 
+// streamKey returns a key that identifies the script for the params given with
+// --src and --dst, or an empty string if neither was given.
+func (c *Command) streamKey() string {
+	if c.Src == "" && c.Dst == "" {
+		return ""
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%s", c.Src, c.Dst)
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
 func createFile(path string) (f *os.File, err error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
 		return nil, err
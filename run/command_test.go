@@ -2,6 +2,8 @@ package run
 
 import (
 	"bytes"
+	"compress/flate"
+	"compress/gzip"
 	"fmt"
 	"go/parser"
 	"go/token"
@@ -122,6 +124,3614 @@ func TestUint64(t *testing.T) {
 	}
 }
 
+// Tests named types with a basic underlying type as arguments.
+func TestNamedBasicType(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "npf.io/gorram/run/_testfuncs",
+		Function: "ToFahrenheit",
+		Args:     []string{"100"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	out := stdout.String()
+	expected := "212\n"
+	if out != expected {
+		t.Errorf("Expected %q but got %q", expected, out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests that errors converting a named type's underlying type name the named
+// type.
+func TestNamedBasicTypeError(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "npf.io/gorram/run/_testfuncs",
+		Function: "ToFahrenheit",
+		Args:     []string{"hot"},
+		Cache:    dir,
+		Env:      env,
+	}
+	if err := Run(c); err == nil {
+		t.Fatal("Expected an error but got none")
+	}
+	expected := `invalid value "hot" for c (testfuncs.Celsius): invalid syntax`
+	if msg := stderr.String(); !strings.Contains(msg, expected) {
+		t.Errorf("Expected stderr to contain %q but got %q", expected, msg)
+	}
+}
+
+// Tests a named type from a package with the same name as another package the
+// script imports, which must be imported with an alias.
+func TestImportAlias(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "npf.io/gorram/run/_testfuncs",
+		Function: "Unescaped",
+		Args:     []string{"<b>hi</b>"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	out := stdout.String()
+	expected := "<b>hi</b>\n"
+	if out != expected {
+		t.Errorf("Expected %q but got %q", expected, out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests passing the name of a constant for an argument of a named type.
+func TestNamedTypeConstant(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "npf.io/gorram/run/_testfuncs",
+		Function: "DaysIn",
+		Args:     []string{"February", "2024"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	out := stdout.String()
+	expected := "29\n"
+	if out != expected {
+		t.Errorf("Expected %q but got %q", expected, out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests passing the name of a constant of a basic type in the function's
+// package.
+func TestBasicTypeConstant(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "npf.io/gorram/run/_testfuncs",
+		Function: "Sum",
+		Args:     []string{"Dozen", "1"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	out := stdout.String()
+	expected := "13\n"
+	if out != expected {
+		t.Errorf("Expected %q but got %q", expected, out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests passing the name of an untyped constant in the function's package for
+// a basic type.
+func TestUntypedConstants(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "StatusText",
+			pkg:      "net/http",
+			function: "StatusText",
+			args:     []string{"StatusTeapot"},
+			expected: "I'm a teapot\n",
+		},
+		{
+			// crypto/tls also has uint16 cipher suite constants.
+			name:     "AmongTyped",
+			pkg:      "crypto/tls",
+			function: "VersionName",
+			args:     []string{"VersionTLS12"},
+			expected: "TLS 1.2\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package:  test.pkg,
+				Function: test.function,
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// Tests giving a number with a type that has constants declared for it.
+func TestTimeDateConstant(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "time",
+		Function: "Date",
+		Args:     []string{"2024", "March", "1", "0", "0", "0", "0", "UTC"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	out := stdout.String()
+	expected := "2024-03-01 00:00:00 +0000 UTC\n"
+	if out != expected {
+		t.Errorf("Expected %q but got %q", expected, out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests that an unknown constant name lists the valid names.
+func TestUnknownConstant(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "npf.io/gorram/run/_testfuncs",
+		Function: "DaysIn",
+		Args:     []string{"Smarch", "2024"},
+		Cache:    dir,
+		Env:      env,
+	}
+	if err := Run(c); err == nil {
+		t.Fatal("Expected an error but got none")
+	}
+	expected := `unknown value "Smarch" for m (time.Month), expected a number or one of: April, August,`
+	if msg := stderr.String(); !strings.Contains(msg, expected) {
+		t.Errorf("Expected stderr to contain %q but got %q", expected, msg)
+	}
+}
+
+// func Jacobi(x, y *Int) int
+// Tests pointer arguments that implement encoding.TextUnmarshaler.
+func TestBigJacobi(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "math/big",
+		Function: "Jacobi",
+		Args:     []string{"1001", "9907"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	out := stdout.String()
+	expected := "-1\n"
+	if out != expected {
+		t.Errorf("Expected %q but got %q", expected, out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// func PrefixFrom(ip Addr, bits int) Prefix
+// Tests value arguments whose pointer implements encoding.TextUnmarshaler.
+func TestNetipPrefixFrom(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "net/netip",
+		Function: "PrefixFrom",
+		Args:     []string{"10.1.2.3", "8"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	out := stdout.String()
+	expected := "10.1.2.3/8\n"
+	if out != expected {
+		t.Errorf("Expected %q but got %q", expected, out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests finding a parser function for an argument type in the type's package.
+func TestParserFunc(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		parsers  []string
+		expected string
+	}{
+		{name: "Default", expected: "a\n"},
+		{name: "Chosen", parsers: []string{"CompilePOSIX"}, expected: "ab\n"},
+		{name: "ChosenForParam", parsers: []string{"re=CompilePOSIX"}, expected: "ab\n"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package:  "npf.io/gorram/run/_testfuncs",
+				Function: "FindString",
+				Args:     []string{"a|ab", "abc"},
+				Parsers:  test.parsers,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// Tests that parsers chosen with -p that no param can use are caught before the
+// script is generated.
+func TestParserFuncErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		parsers  []string
+		expected string
+	}{
+		{
+			name:     "Typo",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "FindString",
+			parsers:  []string{"CompilPOSIX"},
+			expected: `unknown parser "CompilPOSIX" for -p, expected one of: Compile, CompilePOSIX, MustCompile, MustCompilePOSIX, UnmarshalText`,
+		},
+		{
+			name:     "NoChoices",
+			pkg:      "strings",
+			function: "ToUpper",
+			parsers:  []string{"Parse"},
+			expected: `unknown parser "Parse" for -p, strings.ToUpper has no args with parsers to choose from`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			// a script generated without -p is cached first, which mustn't
+			// hide the error.
+			cached := &Command{
+				Package:  test.pkg,
+				Function: test.function,
+				Cache:    dir,
+				Env: Env{
+					Stderr: &bytes.Buffer{},
+					Stdout: &bytes.Buffer{},
+				},
+			}
+			if _, err := cached.Generate(); err != nil {
+				t.Fatal(err)
+			}
+			c := &Command{
+				Package:  test.pkg,
+				Function: test.function,
+				Parsers:  test.parsers,
+				Cache:    dir,
+				Env: Env{
+					Stderr: &bytes.Buffer{},
+					Stdout: &bytes.Buffer{},
+				},
+			}
+			_, err = c.Generate()
+			if err == nil {
+				t.Fatal("Expected an error but got none")
+			}
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected error to contain %q but got %q", test.expected, err)
+			}
+		})
+	}
+}
+
+// Tests that an unknown --from format is an error even with a cached script.
+func TestUnknownFromFormat(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	cached := &Command{
+		Package:  "fmt",
+		Function: "Sprint",
+		Cache:    dir,
+		Env: Env{
+			Stderr: &bytes.Buffer{},
+			Stdout: &bytes.Buffer{},
+		},
+	}
+	if _, err := cached.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	c := &Command{
+		Package:  "fmt",
+		Function: "Sprint",
+		From:     "yaml",
+		Cache:    dir,
+		Env: Env{
+			Stderr: &bytes.Buffer{},
+			Stdout: &bytes.Buffer{},
+		},
+	}
+	_, err = c.Generate()
+	if err == nil {
+		t.Fatal("Expected an error but got none")
+	}
+	expected := `unknown format "yaml" for --from, expected json`
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error to contain %q but got %q", expected, err)
+	}
+}
+
+// Tests the formats accepted for time.Time and time.Duration arguments.
+func TestTimeArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		function string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "RFC3339",
+			function: "AddTo",
+			args:     []string{"2024-02-29T23:00:00-05:00", "90m"},
+			expected: "2024-03-01T05:30:00Z\n",
+		},
+		{
+			name:     "Date",
+			function: "UTCDate",
+			args:     []string{"2024-02-29"},
+			expected: "2024-02-29\n",
+		},
+		{
+			name:     "UnixSeconds",
+			function: "UTCDate",
+			args:     []string{"86400"},
+			expected: "1970-01-02\n",
+		},
+		{
+			name:     "UnixMilliseconds",
+			function: "UTCDate",
+			args:     []string{"1700000000000"},
+			expected: "2023-11-14\n",
+		},
+		{
+			name:     "Now",
+			function: "UTCDate",
+			args:     []string{"now"},
+			expected: time.Now().UTC().Format("2006-01-02") + "\n",
+		},
+		{
+			name:     "Offset",
+			function: "UTCDate",
+			args:     []string{"-48h"},
+			expected: time.Now().Add(-48*time.Hour).UTC().Format("2006-01-02") + "\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package:  "npf.io/gorram/run/_testfuncs",
+				Function: test.function,
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// Tests that the wrong number of args prints the usage, including the formats
+// accepted for each arg.
+func TestWrongArgCountUsage(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "npf.io/gorram/run/_testfuncs",
+		Function: "AddTo",
+		Args:     []string{"now"},
+		Cache:    dir,
+		Env:      env,
+	}
+	if err := Run(c); err == nil {
+		t.Fatal("Expected an error but got none")
+	}
+	msg := stderr.String()
+	for _, expected := range []string{
+		"Expected 2 arguments, but got 1 args.  Missing d.",
+		"Usage: gorram npf.io/gorram/run/_testfuncs AddTo <t> <d>",
+		"  t  time.Time      RFC3339, a date like 2006-01-02, Unix seconds or milliseconds, now, or an offset from now like -2h",
+		"  d  time.Duration  a duration like 1h30m or 250ms",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected stderr to contain %q but got %q", expected, msg)
+		}
+	}
+}
+
+// func Join(elems []string, sep string) string
+// func Join(elem ...string) string
+// Tests slice and variadic arguments.
+func TestSliceArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "CommaSeparated",
+			pkg:      "strings",
+			function: "Join",
+			args:     []string{"a,b,c", "+"},
+			expected: "a+b+c\n",
+		},
+		{
+			name:     "RepeatedFlags",
+			pkg:      "strings",
+			function: "Join",
+			args:     []string{"--elems=a", "+", "--elems", "b"},
+			expected: "a+b\n",
+		},
+		{
+			name:     "Variadic",
+			pkg:      "path",
+			function: "Join",
+			args:     []string{"a", "b", "c"},
+			expected: "a/b/c\n",
+		},
+		{
+			name:     "VariadicEmpty",
+			pkg:      "path",
+			function: "Join",
+			expected: "\n",
+		},
+		{
+			name:     "VariadicConverted",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Sum",
+			args:     []string{"1", "2", "0x10"},
+			expected: "19\n",
+		},
+		{
+			name:     "VariadicBytes",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "AppendBytes",
+			args:     []string{"-", "104", "105"},
+			expected: "hi\n",
+		},
+		{
+			name:     "VariadicWithCommas",
+			pkg:      "path",
+			function: "Join",
+			args:     []string{"a,b", "c"},
+			expected: "a,b/c\n",
+		},
+		{
+			name:     "FlagWithCommas",
+			pkg:      "strings",
+			function: "Join",
+			args:     []string{"--elems=a,b", "--elems=c", "+"},
+			expected: "a,b+c\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package:  test.pkg,
+				Function: test.function,
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// Tests struct arguments.
+func TestStructArgs(t *testing.T) {
+	t.Parallel()
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`{"X": 5, "Label": "file"}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		function string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "Fields",
+			function: "Describe",
+			args:     []string{"--X=1", "--Y", "2", "--Label=a", "--Tags=x", "--Tags=y"},
+			expected: "a(1,2)[x y]\n",
+		},
+		{
+			name:     "QualifiedFields",
+			function: "Describe",
+			args:     []string{"--p.X=3", "--p.Label=b"},
+			expected: "b(3,0)[]\n",
+		},
+		{
+			name:     "JSON",
+			function: "Describe",
+			args:     []string{`{"X": 1, "Y": 2, "Tags": ["z"]}`},
+			expected: "(1,2)[z]\n",
+		},
+		{
+			name:     "File",
+			function: "Describe",
+			args:     []string{"@" + f.Name()},
+			expected: "file(5,0)[]\n",
+		},
+		{
+			name:     "FileAndFields",
+			function: "Describe",
+			args:     []string{"--p=@" + f.Name(), "--Y=4"},
+			expected: "file(5,4)[]\n",
+		},
+		{
+			name:     "Stdin",
+			function: "Describe",
+			stdin:    `{"X": 7, "Label": "stdin"}`,
+			expected: "stdin(7,0)[]\n",
+		},
+		{
+			name:     "Omitted",
+			function: "Describe",
+			expected: "(0,0)[]\n",
+		},
+		{
+			name:     "Pointer",
+			function: "Scale",
+			args:     []string{"--X=1", "--Y=2", "3"},
+			expected: "(3,6)\n",
+		},
+		{
+			name:     "PointerJSON",
+			function: "Scale",
+			args:     []string{`{"X": 1}`, "2"},
+			expected: "(2,0)\n",
+		},
+		{
+			name:     "PointerNil",
+			function: "Scale",
+			args:     []string{"nil", "2"},
+			expected: "nil\n",
+		},
+	}
+	// the subtests are in a group so that the file isn't removed until they're
+	// all done.
+	t.Run("group", func(t *testing.T) {
+		for _, test := range tests {
+			test := test
+			t.Run(test.name, func(t *testing.T) {
+				t.Parallel()
+				dir, err := ioutil.TempDir("", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.Remove(dir)
+				stderr := &bytes.Buffer{}
+				stdout := &bytes.Buffer{}
+				env := Env{
+					Stderr: stderr,
+					Stdout: stdout,
+					Stdin:  strings.NewReader(test.stdin),
+				}
+				c := &Command{
+					Package:  "npf.io/gorram/run/_testfuncs",
+					Function: test.function,
+					Args:     test.args,
+					Cache:    dir,
+					Env:      env,
+				}
+				err = Run(c)
+				checkRunErr(err, c.script(), t)
+				out := stdout.String()
+				if out != test.expected {
+					t.Errorf("Expected %q but got %q", test.expected, out)
+				}
+				if msg := stderr.String(); msg != "" {
+					t.Errorf("Expected no stderr output but got %q", msg)
+				}
+			})
+		}
+	})
+}
+
+// Tests interface and pointer arguments given as the name of a global.
+func TestGlobalArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		function string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "Var",
+			function: "Greet",
+			args:     []string{"testfuncs.English"},
+			expected: "hello\n",
+		},
+		{
+			name:     "VarSamePackage",
+			function: "Greet",
+			args:     []string{"English"},
+			expected: "hello\n",
+		},
+		{
+			name:     "Constructor",
+			function: "Greet",
+			args:     []string{"npf.io/gorram/run/_testfuncs.NewFrench"},
+			expected: "bonjour\n",
+		},
+		{
+			name:     "Nil",
+			function: "Greet",
+			args:     []string{"nil"},
+			expected: "silence\n",
+		},
+		{
+			name:     "OtherPackage",
+			function: "Encode",
+			args:     []string{"base64.URLEncoding", "??>"},
+			expected: "Pz8-\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package:  "npf.io/gorram/run/_testfuncs",
+				Function: test.function,
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// func Checksum(data []byte, tab *Table) uint32
+// Tests a pointer argument that can only be given as the name of a global.
+func TestPointerGlobalArg(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+		Stdin:  strings.NewReader("hello"),
+	}
+	c := &Command{
+		Package:  "hash/crc32",
+		Function: "Checksum",
+		Args:     []string{"-", "crc32.IEEETable"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	expected := "907060870\n"
+	if out := stdout.String(); out != expected {
+		t.Errorf("Expected %q but got %q", expected, out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests the errors for bad names of globals.
+func TestGlobalArgErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		function string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "UnknownName",
+			function: "Greet",
+			args:     []string{"testfuncs.German"},
+			expected: `unknown value "testfuncs.German" for g (testfuncs.Greeter), expected one of: nil, testfuncs.English, testfuncs.NewFrench, testfuncs.NewKlingon`,
+		},
+		{
+			name:     "ConstructorError",
+			function: "Greet",
+			args:     []string{"NewKlingon"},
+			expected: `invalid value "NewKlingon" for g: no klingon speakers available`,
+		},
+		{
+			name:     "UnknownPointerName",
+			function: "Encode",
+			args:     []string{"base64.Foo", "x"},
+			expected: `unknown value "base64.Foo" for enc (*base64.Encoding), expected one of: nil, base64.RawStdEncoding, base64.RawURLEncoding, base64.StdEncoding, base64.URLEncoding`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: &bytes.Buffer{},
+			}
+			c := &Command{
+				Package:  "npf.io/gorram/run/_testfuncs",
+				Function: test.function,
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			if err := Run(c); err == nil {
+				t.Fatal("Expected an error but got none")
+			}
+			if msg := stderr.String(); !strings.Contains(msg, test.expected) {
+				t.Errorf("Expected stderr to contain %q but got %q", test.expected, msg)
+			}
+		})
+	}
+}
+
+// Tests Go expressions given for arguments with -e.
+func TestExprArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "CompositeLiteral",
+			pkg:      "strings",
+			function: "Join",
+			args:     []string{"-e", `[]string{"a", "b"}`, "+"},
+			expected: "a+b\n",
+		},
+		{
+			name:     "UntypedConstant",
+			pkg:      "math",
+			function: "Max",
+			args:     []string{"1", "-e", "math.Pi * 2"},
+			expected: "6.283185307179586\n",
+		},
+		{
+			name:     "Variadic",
+			pkg:      "path",
+			function: "Join",
+			args:     []string{"-e", `[]string{"a", "b"}`},
+			expected: "a/b\n",
+		},
+		{
+			name:     "Src",
+			pkg:      "encoding/base64",
+			function: "StdEncoding.EncodeToString",
+			args:     []string{"-e", `[]byte("hi")`},
+			expected: "aGk=\n",
+		},
+		{
+			name:     "Interface",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Greet",
+			args:     []string{"-e", "testfuncs.NewFrench()"},
+			expected: "bonjour\n",
+		},
+		{
+			name:     "AfterDashes",
+			pkg:      "strings",
+			function: "Repeat",
+			args:     []string{"--", "-e", "2"},
+			expected: "-e-e\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package: test.pkg,
+				Args:    test.args,
+				Cache:   dir,
+				Env:     env,
+			}
+			if parts := strings.Split(test.function, "."); len(parts) == 2 {
+				c.GlobalVar, c.Function = parts[0], parts[1]
+			} else {
+				c.Function = test.function
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// Tests that bad expressions are caught before the script is generated.
+func TestExprArgErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "WrongType",
+			pkg:      "math",
+			function: "Sqrt",
+			args:     []string{"-e", `"x"`},
+			expected: `invalid expression "\"x\"" for x: untyped string is not assignable to float64`,
+		},
+		{
+			name:     "UnknownPackage",
+			pkg:      "math",
+			function: "Sqrt",
+			args:     []string{"-e", "zzz.Pi"},
+			expected: `invalid expression "zzz.Pi" for x: unknown package zzz, only packages used by math may be used`,
+		},
+		{
+			name:     "Syntax",
+			pkg:      "math",
+			function: "Sqrt",
+			args:     []string{"-e", "1 +"},
+			expected: `invalid expression "1 +" for x`,
+		},
+		{
+			name:     "Missing",
+			pkg:      "math",
+			function: "Sqrt",
+			args:     []string{"-e"},
+			expected: "missing expression after -e",
+		},
+		{
+			name:     "IntOverflow",
+			pkg:      "unicode/utf8",
+			function: "RuneLen",
+			args:     []string{"-e", "99999999999999999999"},
+			expected: `invalid expression "99999999999999999999" for r: constant overflows rune`,
+		},
+		{
+			name:     "UnsignedOverflow",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "DoubleUint64",
+			args:     []string{"-e", "-1"},
+			expected: `invalid expression "-1" for a: constant overflows uint64`,
+		},
+		{
+			name:     "Truncated",
+			pkg:      "unicode/utf8",
+			function: "RuneLen",
+			args:     []string{"-e", "2.5"},
+			expected: `invalid expression "2.5" for r: constant is truncated to rune`,
+		},
+		{
+			name:     "FloatOverflow",
+			pkg:      "math",
+			function: "Sqrt",
+			args:     []string{"-e", "1e400"},
+			expected: `invalid expression "1e400" for x: constant overflows float64`,
+		},
+		{
+			name:     "DefaultTypeOverflow",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "TypeOf",
+			args:     []string{"-e", "99999999999999999999"},
+			expected: `invalid expression "99999999999999999999" for v: constant overflows int`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			c := &Command{
+				Package:  test.pkg,
+				Function: test.function,
+				Args:     test.args,
+				Cache:    dir,
+				Env: Env{
+					Stderr: &bytes.Buffer{},
+					Stdout: &bytes.Buffer{},
+				},
+			}
+			_, err = c.Generate()
+			if err == nil {
+				t.Fatal("Expected an error but got none")
+			}
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected error to contain %q but got %q", test.expected, err)
+			}
+		})
+	}
+}
+
+// func Sleep(ctx context.Context, d time.Duration) (string, error)
+// Tests that context args are supplied, and cancelled after the timeout.
+func TestContextArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		args     []string
+		timeout  time.Duration
+		expected string
+		stderr   string
+	}{
+		{
+			name:     "NoTimeout",
+			args:     []string{"10ms"},
+			expected: "done\n",
+		},
+		{
+			name:     "Named",
+			args:     []string{"--d=10ms"},
+			timeout:  time.Minute,
+			expected: "done\n",
+		},
+		{
+			name:    "TimedOut",
+			args:    []string{"1m"},
+			timeout: 100 * time.Millisecond,
+			stderr:  "context deadline exceeded",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package:  "npf.io/gorram/run/_testfuncs",
+				Function: "Sleep",
+				Args:     test.args,
+				Timeout:  test.timeout,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			if test.stderr != "" {
+				if err == nil {
+					t.Fatal("Expected an error but got none")
+				}
+				if msg := stderr.String(); !strings.Contains(msg, test.stderr) {
+					t.Errorf("Expected stderr to contain %q but got %q", test.stderr, msg)
+				}
+				return
+			}
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// func CommandContext(ctx context.Context, name string, arg ...string) *Cmd
+// Tests that a struct result whose reader field is nil is printed, rather than
+// copied from.
+func TestNilReaderField(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "os/exec",
+		Function: "CommandContext",
+		Args:     []string{"true"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	// *exec.Cmd prints as its command line.
+	if out := stdout.String(); !strings.Contains(out, "true") {
+		t.Errorf("Expected output to contain %q but got %q", "true", out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests the types of src that are read from stdin or a file.
+func TestSrcTypes(t *testing.T) {
+	t.Parallel()
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("abbc\nde"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "StringStdin",
+			pkg:      "strings",
+			function: "ToUpper",
+			stdin:    "abc\n",
+			expected: "ABC\n",
+		},
+		{
+			name:     "StringArg",
+			pkg:      "strings",
+			function: "ToUpper",
+			args:     []string{"abc"},
+			expected: "ABC\n",
+		},
+		{
+			name:     "StringFile",
+			pkg:      "strings",
+			function: "ToUpper",
+			args:     []string{"@" + f.Name()},
+			expected: "ABBC\nDE\n",
+		},
+		{
+			name:     "RuneReader",
+			pkg:      "regexp",
+			function: "MatchReader",
+			args:     []string{"b+"},
+			stdin:    "abbc",
+			expected: "true\n",
+		},
+		{
+			name:     "ReadSeekerStdin",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Tail",
+			args:     []string{"--n=5"},
+			stdin:    "hello world",
+			expected: "world\n",
+		},
+		{
+			name:     "ReadSeekerFile",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Tail",
+			args:     []string{f.Name(), "2"},
+			expected: "de\n",
+		},
+		{
+			name:     "ReaderAt",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "ByteAt",
+			args:     []string{"--off=2"},
+			stdin:    "xyz",
+			expected: "z\n",
+		},
+		{
+			name:     "File",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "FileSize",
+			stdin:    "12345",
+			expected: "5\n",
+		},
+		{
+			name:     "BufioReader",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "FirstLine",
+			args:     []string{f.Name()},
+			expected: "abbc\n",
+		},
+	}
+	// the subtests are in a group so that the file isn't removed until they're
+	// all done.
+	t.Run("group", func(t *testing.T) {
+		for _, test := range tests {
+			test := test
+			t.Run(test.name, func(t *testing.T) {
+				t.Parallel()
+				dir, err := ioutil.TempDir("", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.Remove(dir)
+				stderr := &bytes.Buffer{}
+				stdout := &bytes.Buffer{}
+				env := Env{
+					Stderr: stderr,
+					Stdout: stdout,
+					Stdin:  strings.NewReader(test.stdin),
+				}
+				c := &Command{
+					Package:  test.pkg,
+					Function: test.function,
+					Args:     test.args,
+					Cache:    dir,
+					Env:      env,
+				}
+				err = Run(c)
+				checkRunErr(err, c.script(), t)
+				out := stdout.String()
+				if out != test.expected {
+					t.Errorf("Expected %q but got %q", test.expected, out)
+				}
+				if msg := stderr.String(); msg != "" {
+					t.Errorf("Expected no stderr output but got %q", msg)
+				}
+			})
+		}
+	})
+}
+
+// Tests the types of dst the function may write its output to, and that a
+// newline is only added to output that doesn't end with one.
+func TestDstTypes(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		function string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "File",
+			function: "FileWrite",
+			args:     []string{"abc"},
+			expected: "abc\n",
+		},
+		{
+			name:     "FileNewline",
+			function: "FileWrite",
+			args:     []string{`"abc\n"`},
+			expected: "abc\n",
+		},
+		{
+			name:     "BufioWriter",
+			function: "BufioWrite",
+			args:     []string{"abc"},
+			expected: "abc\n",
+		},
+		{
+			name:     "BufioWriterNewline",
+			function: "BufioWrite",
+			args:     []string{`"abc\n"`},
+			expected: "abc\n",
+		},
+		{
+			name:     "StringsBuilder",
+			function: "BuilderWrite",
+			args:     []string{"abc"},
+			expected: "abc\n",
+		},
+		{
+			name:     "WriteCloser",
+			function: "CloserWrite",
+			args:     []string{`"abc\n"`},
+			expected: "abc\n",
+		},
+		{
+			name:     "StringWriter",
+			function: "StringWrite",
+			args:     []string{"abc"},
+			expected: "abc\n",
+		},
+		{
+			name:     "Empty",
+			function: "StringWrite",
+			args:     []string{`""`},
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package:  "npf.io/gorram/run/_testfuncs",
+				Function: test.function,
+				Args:     test.args,
+				Quoted:   true,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// Tests functions that take more than one stream of input, which are read
+// from files, or one of them from stdin.
+func TestMultipleStreams(t *testing.T) {
+	t.Parallel()
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("hi"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		quoted   bool
+		args     []string
+		stdin    string
+		expected string
+		errMsg   string
+	}{
+		{
+			name:     "BytesFiles",
+			pkg:      "bytes",
+			function: "Equal",
+			args:     []string{f.Name(), f.Name()},
+			expected: "true\n",
+		},
+		{
+			name:     "BytesSrcStdin",
+			pkg:      "bytes",
+			function: "Equal",
+			args:     []string{f.Name()},
+			stdin:    "hi",
+			expected: "true\n",
+		},
+		{
+			name:     "BytesStdin",
+			pkg:      "bytes",
+			function: "Equal",
+			args:     []string{f.Name(), "-"},
+			stdin:    "ho",
+			expected: "false\n",
+		},
+		{
+			name:     "ReaderFile",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Concat",
+			args:     []string{f.Name()},
+			stdin:    "!",
+			expected: "hi!\n",
+		},
+		{
+			name:     "ReaderStdin",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Concat",
+			args:     []string{"-", f.Name()},
+			stdin:    "!",
+			expected: "!hi\n",
+		},
+		{
+			name:     "BytesQuoted",
+			pkg:      "bytes",
+			function: "Contains",
+			quoted:   true,
+			args:     []string{f.Name(), `"i"`},
+			expected: "true\n",
+		},
+		{
+			name:     "BytesHex",
+			pkg:      "bytes",
+			function: "Contains",
+			args:     []string{f.Name(), "hex:6869"},
+			expected: "true\n",
+		},
+		{
+			name:     "BytesNotAFile",
+			pkg:      "bytes",
+			function: "Contains",
+			args:     []string{f.Name(), "ell"},
+			errMsg:   "open ell: no such file or directory",
+		},
+		{
+			name:     "StdinTwice",
+			pkg:      "bytes",
+			function: "Equal",
+			args:     []string{"-"},
+			errMsg:   "Only one argument may be read from stdin.",
+		},
+	}
+	// the subtests are in a group so that the file isn't removed until they're
+	// all done.
+	t.Run("group", func(t *testing.T) {
+		for _, test := range tests {
+			test := test
+			t.Run(test.name, func(t *testing.T) {
+				t.Parallel()
+				dir, err := ioutil.TempDir("", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.Remove(dir)
+				stderr := &bytes.Buffer{}
+				stdout := &bytes.Buffer{}
+				env := Env{
+					Stderr: stderr,
+					Stdout: stdout,
+					Stdin:  strings.NewReader(test.stdin),
+				}
+				c := &Command{
+					Package:  test.pkg,
+					Function: test.function,
+					Args:     test.args,
+					Quoted:   test.quoted,
+					Cache:    dir,
+					Env:      env,
+				}
+				err = Run(c)
+				if test.errMsg != "" {
+					if err == nil {
+						t.Fatal("Expected an error but got none")
+					}
+					if msg := stderr.String(); !strings.Contains(msg, test.errMsg) {
+						t.Errorf("Expected stderr to contain %q but got %q", test.errMsg, msg)
+					}
+					return
+				}
+				checkRunErr(err, c.script(), t)
+				out := stdout.String()
+				if out != test.expected {
+					t.Errorf("Expected %q but got %q", test.expected, out)
+				}
+				if msg := stderr.String(); msg != "" {
+					t.Errorf("Expected no stderr output but got %q", msg)
+				}
+			})
+		}
+	})
+}
+
+// Tests []byte dsts, which are sized with a function like hex.EncodedLen, and
+// written to stdout up to the count of bytes the function returns.
+func TestByteDst(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		pkg       string
+		globalVar string
+		function  string
+		stdin     string
+		expected  string
+	}{
+		{
+			name:     "HexEncode",
+			pkg:      "encoding/hex",
+			function: "Encode",
+			stdin:    "hello",
+			expected: "68656c6c6f\n",
+		},
+		{
+			name:     "HexDecode",
+			pkg:      "encoding/hex",
+			function: "Decode",
+			stdin:    "68656c6c6f",
+			expected: "hello\n",
+		},
+		{
+			name:      "Base64Encode",
+			pkg:       "encoding/base64",
+			globalVar: "StdEncoding",
+			function:  "Encode",
+			stdin:     "hello",
+			expected:  "aGVsbG8=\n",
+		},
+		{
+			name:      "Base64Decode",
+			pkg:       "encoding/base64",
+			globalVar: "StdEncoding",
+			function:  "Decode",
+			stdin:     "aGVsbG8=",
+			expected:  "hello\n",
+		},
+		{
+			name:     "MaxEncodedLen",
+			pkg:      "encoding/ascii85",
+			function: "Encode",
+			stdin:    "hello",
+			expected: "BOu!rDZ\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+				Stdin:  strings.NewReader(test.stdin),
+			}
+			c := &Command{
+				Package:   test.pkg,
+				GlobalVar: test.globalVar,
+				Function:  test.function,
+				Cache:     dir,
+				Env:       env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// Tests piping stdin or a file through the writer a function returns, like
+// gzip.NewWriter's, or the hash.Hash it returns, like sha256.New's.
+func TestWriterPipes(t *testing.T) {
+	t.Parallel()
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "Hash",
+			pkg:      "crypto/sha256",
+			function: "New",
+			stdin:    "hello",
+			expected: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824\n",
+		},
+		{
+			name:     "HashFile",
+			pkg:      "crypto/md5",
+			function: "New",
+			args:     []string{f.Name()},
+			expected: "5d41402abc4b2a76b9719d911017c592\n",
+		},
+		{
+			name:     "WriteCloser",
+			pkg:      "encoding/base64",
+			function: "NewEncoder",
+			args:     []string{"base64.StdEncoding"},
+			stdin:    "hello",
+			expected: "aGVsbG8=",
+		},
+		{
+			name:     "WriteCloserFile",
+			pkg:      "encoding/hex",
+			function: "Dumper",
+			args:     []string{f.Name()},
+			expected: "00000000  68 65 6c 6c 6f                                    |hello|\n",
+		},
+		{
+			name:     "Flusher",
+			pkg:      "bufio",
+			function: "NewWriter",
+			stdin:    "hello",
+			expected: "hello",
+		},
+	}
+	// the subtests are in a group so that the file isn't removed until they're
+	// all done.
+	t.Run("group", func(t *testing.T) {
+		for _, test := range tests {
+			test := test
+			t.Run(test.name, func(t *testing.T) {
+				t.Parallel()
+				dir, err := ioutil.TempDir("", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.Remove(dir)
+				stderr := &bytes.Buffer{}
+				stdout := &bytes.Buffer{}
+				env := Env{
+					Stderr: stderr,
+					Stdout: stdout,
+					Stdin:  strings.NewReader(test.stdin),
+				}
+				c := &Command{
+					Package:  test.pkg,
+					Function: test.function,
+					Args:     test.args,
+					Cache:    dir,
+					Env:      env,
+				}
+				err = Run(c)
+				checkRunErr(err, c.script(), t)
+				out := stdout.String()
+				if out != test.expected {
+					t.Errorf("Expected %q but got %q", test.expected, out)
+				}
+				if msg := stderr.String(); msg != "" {
+					t.Errorf("Expected no stderr output but got %q", msg)
+				}
+			})
+		}
+	})
+}
+
+// Tests that output piped through a writer isn't given a newline, which would
+// corrupt gzip's.
+func TestGzipWriterPipe(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+		Stdin:  strings.NewReader("hello"),
+	}
+	c := &Command{
+		Package:  "compress/gzip",
+		Function: "NewWriter",
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	r, err := gzip.NewReader(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Errorf("Expected %q but got %q", "hello", b)
+	}
+}
+
+// Tests copying the reader a function returns, like gzip.NewReader's, to
+// stdout.
+func TestReaderPipes(t *testing.T) {
+	t.Parallel()
+	gzipped := &bytes.Buffer{}
+	zw := gzip.NewWriter(gzipped)
+	if _, err := zw.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	deflated := &bytes.Buffer{}
+	fw, err := flate.NewWriter(deflated, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(gzipped.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "ReaderErr",
+			pkg:      "compress/gzip",
+			function: "NewReader",
+			stdin:    gzipped.String(),
+			expected: "hello",
+		},
+		{
+			name:     "ReaderFile",
+			pkg:      "compress/gzip",
+			function: "NewReader",
+			args:     []string{f.Name()},
+			expected: "hello",
+		},
+		{
+			name:     "ReadCloser",
+			pkg:      "compress/flate",
+			function: "NewReader",
+			stdin:    deflated.String(),
+			expected: "hello\n",
+		},
+		{
+			name:     "Reader",
+			pkg:      "encoding/base64",
+			function: "NewDecoder",
+			args:     []string{"base64.StdEncoding"},
+			stdin:    "aGVsbG8=",
+			expected: "hello",
+		},
+		{
+			name:     "ReaderArgs",
+			pkg:      "io",
+			function: "LimitReader",
+			args:     []string{"--n=3"},
+			stdin:    "hello",
+			expected: "hel",
+		},
+	}
+	// the subtests are in a group so that the file isn't removed until they're
+	// all done.
+	t.Run("group", func(t *testing.T) {
+		for _, test := range tests {
+			test := test
+			t.Run(test.name, func(t *testing.T) {
+				t.Parallel()
+				dir, err := ioutil.TempDir("", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.Remove(dir)
+				stderr := &bytes.Buffer{}
+				stdout := &bytes.Buffer{}
+				env := Env{
+					Stderr: stderr,
+					Stdout: stdout,
+					Stdin:  strings.NewReader(test.stdin),
+				}
+				c := &Command{
+					Package:  test.pkg,
+					Function: test.function,
+					Args:     test.args,
+					Cache:    dir,
+					Env:      env,
+				}
+				err = Run(c)
+				checkRunErr(err, c.script(), t)
+				out := stdout.String()
+				if out != test.expected {
+					t.Errorf("Expected %q but got %q", test.expected, out)
+				}
+				if msg := stderr.String(); msg != "" {
+					t.Errorf("Expected no stderr output but got %q", msg)
+				}
+			})
+		}
+	})
+}
+
+// Tests that an *os.File a function returns, like os.Create's, isn't copied to
+// stdout like other readers, since it may be write only.
+func TestFileResult(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	name := filepath.Join(dir, "created.txt")
+	c := &Command{
+		Package:  "os",
+		Function: "Create",
+		Args:     []string{name},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	if _, err := os.Stat(name); err != nil {
+		t.Errorf("Expected %s to be created but got %v", name, err)
+	}
+	if out := stdout.String(); !strings.HasPrefix(out, "&{") {
+		t.Errorf("Expected the file to be printed but got %q", out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests guessing which params are the src and dst from their names, and
+// choosing them with --src and --dst.
+func TestStreamParams(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		src      string
+		dst      string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "DstOnly",
+			pkg:      "fmt",
+			function: "Fprintf",
+			args:     []string{"%s-%d", "x", "3"},
+			expected: "x-3\n",
+		},
+		{
+			name:     "StringSrc",
+			pkg:      "io",
+			function: "WriteString",
+			stdin:    "abc\n",
+			expected: "abc\n",
+		},
+		{
+			name:     "NamedSrcDst",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Prefix",
+			args:     []string{"> "},
+			stdin:    "abc",
+			expected: "> abc\n",
+		},
+		{
+			name:     "NamedSrc",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Concat",
+			args:     []string{"-e", `strings.NewReader("a")`},
+			stdin:    "b",
+			expected: "ab\n",
+		},
+		{
+			name:     "SrcFlag",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Concat",
+			src:      "head",
+			args:     []string{"-", "-e", `strings.NewReader("b")`},
+			stdin:    "a",
+			expected: "ab\n",
+		},
+		{
+			name:     "DstFlag",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Emit",
+			dst:      "sink",
+			args:     []string{"abc"},
+			expected: "abc\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+				Stdin:  strings.NewReader(test.stdin),
+			}
+			c := &Command{
+				Package:  test.pkg,
+				Function: test.function,
+				Args:     test.args,
+				Src:      test.src,
+				Dst:      test.dst,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// Tests the errors for params given with --src and --dst that can't be used.
+func TestStreamParamErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		src      string
+		dst      string
+		expected string
+	}{
+		{
+			name:     "NoParam",
+			src:      "nope",
+			expected: "npf.io/gorram/run/_testfuncs.Emit has no param nope for --src",
+		},
+		{
+			name:     "NotStream",
+			dst:      "s",
+			expected: "param s of type string can't be the --dst",
+		},
+		{
+			name:     "NotStreamSrc",
+			src:      "s",
+			expected: "param s of type string can't be the --src",
+		},
+		{
+			name:     "NotSrc",
+			src:      "sink",
+			expected: "param sink of type io.Writer can't be the --src",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			c := &Command{
+				Package:  "npf.io/gorram/run/_testfuncs",
+				Function: "Emit",
+				Src:      test.src,
+				Dst:      test.dst,
+				Cache:    dir,
+				Env: Env{
+					Stderr: &bytes.Buffer{},
+					Stdout: &bytes.Buffer{},
+				},
+			}
+			_, err = c.Generate()
+			if err == nil {
+				t.Fatal("Expected an error but got none")
+			}
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected error to contain %q but got %q", test.expected, err)
+			}
+		})
+	}
+}
+
+// Tests calling generic functions.
+func TestGenericFuncs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		typeArgs []string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "ElemType",
+			pkg:      "slices",
+			function: "Max",
+			typeArgs: []string{"int"},
+			args:     []string{"3,10,2"},
+			expected: "10\n",
+		},
+		{
+			name:     "SliceType",
+			pkg:      "slices",
+			function: "Max",
+			typeArgs: []string{"[]string"},
+			args:     []string{"b,c,a"},
+			expected: "c\n",
+		},
+		{
+			name:     "QualifiedType",
+			pkg:      "slices",
+			function: "Contains",
+			typeArgs: []string{"time.Duration"},
+			args:     []string{"1s,1m", "60s"},
+			expected: "true\n",
+		},
+		{
+			name:     "InferredFromExpr",
+			pkg:      "cmp",
+			function: "Compare",
+			args:     []string{"-e", "2.5", "3"},
+			expected: "-1\n",
+		},
+		{
+			name:     "InferredFromMixedConstants",
+			pkg:      "cmp",
+			function: "Compare",
+			args:     []string{"-e", "2", "-e", "2.5"},
+			expected: "-1\n",
+		},
+		{
+			name:     "InferredFromTildeCoreType",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Last",
+			args:     []string{"-e", "testfuncs.Temps{1.5, 2.5}"},
+			expected: "2.5\n",
+		},
+		{
+			name:     "InferredFromCoreType",
+			pkg:      "slices",
+			function: "Index",
+			args:     []string{"-e", "[]string{\"a\", \"b\"}", "-e", "\"b\""},
+			expected: "1\n",
+		},
+		{
+			name:     "NoCoreType",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Total",
+			typeArgs: []string{"float64"},
+			args:     []string{"1.5", "2"},
+			expected: "3.5\n",
+		},
+		{
+			name:     "NonGenericParam",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Pick",
+			typeArgs: []string{"int"},
+			args:     []string{"false", "1", "2"},
+			expected: "2\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package:  test.pkg,
+				Function: test.function,
+				TypeArgs: test.typeArgs,
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// Tests the errors for generic functions that can't be instantiated.
+func TestGenericFuncErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		typeArgs []string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "CantInfer",
+			pkg:      "slices",
+			function: "Max",
+			expected: "can't infer S, E for Max[S ~[]E, E cmp.Ordered], give the types in brackets after the function's name, e.g. Max[int]",
+		},
+		{
+			name:     "Unsatisfied",
+			pkg:      "slices",
+			function: "Max",
+			typeArgs: []string{"bool"},
+			expected: "can't instantiate Max with [[]bool, bool]: bool does not satisfy cmp.Ordered",
+		},
+		{
+			name:     "TooMany",
+			pkg:      "cmp",
+			function: "Compare",
+			typeArgs: []string{"int", "int"},
+			expected: "Compare has 1 type params, but got 2 type args",
+		},
+		{
+			name:     "NotAType",
+			pkg:      "cmp",
+			function: "Compare",
+			typeArgs: []string{"3"},
+			expected: `invalid type "3" for Compare: not a type`,
+		},
+		{
+			name:     "NotGeneric",
+			pkg:      "strings",
+			function: "Repeat",
+			typeArgs: []string{"int"},
+			expected: "strings.Repeat is not generic, but was given type args",
+		},
+		{
+			name:     "ConflictingExprs",
+			pkg:      "cmp",
+			function: "Compare",
+			args:     []string{"-e", "int8(2)", "-e", "int16(3)"},
+			expected: "can't infer type args for Compare[T cmp.Ordered]: int16(3) for y is int16, but y is int8",
+		},
+		{
+			name:     "ConflictingConstants",
+			pkg:      "cmp",
+			function: "Compare",
+			args:     []string{"-e", "2", "-e", "\"a\""},
+			expected: "can't infer T for Compare[T cmp.Ordered]: mismatched types untyped int and untyped string",
+		},
+		{
+			name:     "ConflictingCoreType",
+			pkg:      "slices",
+			function: "Contains",
+			args:     []string{"-e", "[]int{1}", "-e", "string(\"a\")"},
+			expected: "can't infer type args for Contains[S ~[]E, E comparable]: S is []int, which doesn't match []string",
+		},
+		{
+			name:     "ConflictingTypeArg",
+			pkg:      "npf.io/gorram/run/_testfuncs",
+			function: "Last",
+			typeArgs: []string{"[]int"},
+			args:     []string{"-e", "[]string{\"a\"}"},
+			expected: "can't infer type args for Last[S ~[]E, E any]: []string{\"a\"} for s is []string, but s is []int",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			c := &Command{
+				Package:  test.pkg,
+				Function: test.function,
+				TypeArgs: test.typeArgs,
+				Args:     test.args,
+				Cache:    dir,
+				Env: Env{
+					Stderr: &bytes.Buffer{},
+					Stdout: &bytes.Buffer{},
+				},
+			}
+			_, err = c.Generate()
+			if err == nil {
+				t.Fatal("Expected an error but got none")
+			}
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected error to contain %q but got %q", test.expected, err)
+			}
+		})
+	}
+}
+
+// Tests that []byte results are written as is, not as a list of numbers.
+func TestByteSliceResults(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		function string
+		args     []string
+		from     string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "Marshal",
+			function: "Marshal",
+			args:     []string{`{"a": 1}`},
+			expected: `{"a":1}` + "\n",
+		},
+		{
+			name:     "MarshalIndentFromStdin",
+			function: "MarshalIndent",
+			args:     []string{"", "  "},
+			from:     "json",
+			stdin:    `{"a": [1, "b"]}`,
+			expected: "{\n  \"a\": [\n    1,\n    \"b\"\n  ]\n}\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+				Stdin:  strings.NewReader(test.stdin),
+			}
+			c := &Command{
+				Package:  "encoding/json",
+				Function: test.function,
+				Args:     test.args,
+				From:     test.from,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// Tests inferring the type of interface{} arguments.
+func TestAnyArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		function string
+		args     []string
+		from     string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "Int",
+			function: "TypeOf",
+			args:     []string{"-12"},
+			expected: "int -12\n",
+		},
+		{
+			name:     "LeadingZero",
+			function: "TypeOf",
+			args:     []string{"0123"},
+			expected: "int 123\n",
+		},
+		{
+			name:     "LeadingZeroNine",
+			function: "TypeOf",
+			args:     []string{"09"},
+			expected: "int 9\n",
+		},
+		{
+			name:     "Hex",
+			function: "TypeOf",
+			args:     []string{"0x1f"},
+			expected: "int 31\n",
+		},
+		{
+			name:     "Float",
+			function: "TypeOf",
+			args:     []string{"1.5"},
+			expected: "float64 1.5\n",
+		},
+		{
+			name:     "Bool",
+			function: "TypeOf",
+			args:     []string{"true"},
+			expected: "bool true\n",
+		},
+		{
+			name:     "QuotedString",
+			function: "TypeOf",
+			args:     []string{`"12"`},
+			expected: "string 12\n",
+		},
+		{
+			name:     "String",
+			function: "TypeOf",
+			args:     []string{"Inf"},
+			expected: "string Inf\n",
+		},
+		{
+			name:     "JSONObject",
+			function: "TypeOf",
+			args:     []string{`{"a": [1, "b"]}`},
+			expected: "map[string]interface {} map[a:[1 b]]\n",
+		},
+		{
+			name:     "Nil",
+			function: "TypeOf",
+			args:     []string{"nil"},
+			expected: "<nil> <nil>\n",
+		},
+		{
+			name:     "FromJSON",
+			function: "TypeOf",
+			from:     "json",
+			stdin:    `[1, 2.5]`,
+			expected: "[]interface {} [1 2.5]\n",
+		},
+		{
+			name:     "FromJSONGiven",
+			function: "TypeOf",
+			args:     []string{"5"},
+			from:     "json",
+			stdin:    `[1, 2.5]`,
+			expected: "int 5\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+				Stdin:  strings.NewReader(test.stdin),
+			}
+			c := &Command{
+				Package:  "npf.io/gorram/run/_testfuncs",
+				Function: test.function,
+				Args:     test.args,
+				From:     test.from,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// func Sprint(a ...any) string
+// Tests inferring the types of variadic interface{} arguments.
+func TestFmtSprint(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "fmt",
+		Function: "Sprint",
+		Args:     []string{"1", "2", "x"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	// Sprint only adds spaces between operands that aren't strings.
+	expected := "1 2x\n"
+	out := stdout.String()
+	if out != expected {
+		t.Errorf("Expected %q but got %q", expected, out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests func arguments given as the name of a function.
+func TestFuncArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "Map",
+			function: "Map",
+			args:     []string{"unicode.ToUpper", "abc"},
+			expected: "ABC\n",
+		},
+		{
+			name:     "TrimFunc",
+			function: "TrimFunc",
+			args:     []string{"123abc456", "unicode.IsDigit"},
+			expected: "abc\n",
+		},
+		{
+			name:     "IndexFunc",
+			function: "IndexFunc",
+			args:     []string{"ab1", "unicode.IsNumber"},
+			expected: "2\n",
+		},
+		{
+			// func() hash.Hash needs hash imported, and sha256 loaded,
+			// though hmac doesn't use it.
+			name:     "HMAC",
+			pkg:      "crypto/hmac",
+			function: "New",
+			args:     []string{"sha256.New", "key"},
+			stdin:    "hello",
+			expected: "9307b3b915efb5171ff14d8cb55fbcc798c6c0ef1456d66ded1a6aa723a58b7b\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+				Stdin:  strings.NewReader(test.stdin),
+			}
+			pkg := test.pkg
+			if pkg == "" {
+				pkg = "strings"
+			}
+			c := &Command{
+				Package:  pkg,
+				Function: test.function,
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// func Map(mapping func(rune) rune, s string) string
+// Tests the error for an unknown function name.
+func TestFuncArgUnknown(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: &bytes.Buffer{},
+	}
+	c := &Command{
+		Package:  "strings",
+		Function: "Map",
+		Args:     []string{"unicode.ToShouting", "abc"},
+		Cache:    dir,
+		Env:      env,
+	}
+	if err := Run(c); err == nil {
+		t.Fatal("Expected an error but got none")
+	}
+	expected := `unknown value "unicode.ToShouting" for mapping (func(rune) rune), expected one of: nil,`
+	if msg := stderr.String(); !strings.Contains(msg, expected) || !strings.Contains(msg, "unicode.ToUpper") {
+		t.Errorf("Expected stderr to contain %q and unicode.ToUpper but got %q", expected, msg)
+	}
+}
+
+// Tests giving arguments by name.
+func TestNamedArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "Mixed",
+			pkg:      "strings",
+			function: "Repeat",
+			args:     []string{"--count=3", "ab"},
+			expected: "ababab\n",
+		},
+		{
+			name:     "AllNamed",
+			pkg:      "strings",
+			function: "Repeat",
+			args:     []string{"--count", "2", "--s=x"},
+			expected: "xx\n",
+		},
+		{
+			name:     "Src",
+			pkg:      "encoding/json",
+			function: "Indent",
+			args:     []string{"--indent=\t", "--prefix="},
+			stdin:    `{"a": 1}`,
+			expected: "{\n\t\"a\": 1\n}\n",
+		},
+		{
+			name:     "ValueAfterDashes",
+			pkg:      "strings",
+			function: "Repeat",
+			args:     []string{"--count=2", "--", "--s"},
+			expected: "--s--s\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+				Stdin:  strings.NewReader(test.stdin),
+			}
+			c := &Command{
+				Package:  test.pkg,
+				Function: test.function,
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// func Repeat(s string, count int) string
+// Tests the errors for bad named arguments.
+func TestNamedArgErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "Unknown",
+			args:     []string{"--cnt=3", "ab"},
+			expected: "Unknown flag --cnt.",
+		},
+		{
+			name:     "Twice",
+			args:     []string{"--count=1", "--count=2", "ab"},
+			expected: "Flag --count given more than once.",
+		},
+		{
+			name:     "Missing",
+			args:     []string{"--count=3"},
+			expected: "Expected 1 arguments, but got 0 args.  Missing s.",
+		},
+		{
+			name:     "MissingValue",
+			args:     []string{"ab", "--count"},
+			expected: "Missing value for --count.",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: &bytes.Buffer{},
+			}
+			c := &Command{
+				Package:  "strings",
+				Function: "Repeat",
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			if err := Run(c); err == nil {
+				t.Fatal("Expected an error but got none")
+			}
+			msg := stderr.String()
+			for _, expected := range []string{test.expected, "Arguments may also be given by name, e.g. --s=<value>."} {
+				if !strings.Contains(msg, expected) {
+					t.Errorf("Expected stderr to contain %q but got %q", expected, msg)
+				}
+			}
+		})
+	}
+}
+
+// Tests arguments given indirectly, from a file, an environment variable, or
+// stdin.
+func TestIndirectArgs(t *testing.T) {
+	t.Parallel()
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("ab\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("GORRAM_TEST_COUNT", "3")
+	defer os.Unsetenv("GORRAM_TEST_COUNT")
+	os.Setenv("GORRAM_TEST_LIST", "a,b")
+	defer os.Unsetenv("GORRAM_TEST_LIST")
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		envArgs  bool
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "File",
+			pkg:      "strings",
+			function: "Repeat",
+			args:     []string{"@" + f.Name(), "2"},
+			expected: "abab\n",
+		},
+		{
+			name:     "Env",
+			pkg:      "strings",
+			function: "Repeat",
+			envArgs:  true,
+			args:     []string{"x", "env:GORRAM_TEST_COUNT"},
+			expected: "xxx\n",
+		},
+		{
+			name:     "LiteralEnv",
+			pkg:      "strings",
+			function: "ToUpper",
+			args:     []string{"env:foo"},
+			expected: "ENV:FOO\n",
+		},
+		{
+			name:     "Stdin",
+			pkg:      "strings",
+			function: "Repeat",
+			args:     []string{"-", "2"},
+			stdin:    "cd\n",
+			expected: "cdcd\n",
+		},
+		{
+			name:     "Escaped",
+			pkg:      "strings",
+			function: "Repeat",
+			args:     []string{"@@a", "2"},
+			expected: "@a@a\n",
+		},
+		{
+			name:     "Named",
+			pkg:      "strings",
+			function: "Repeat",
+			envArgs:  true,
+			args:     []string{"--count=env:GORRAM_TEST_COUNT", "--s", "@" + f.Name()},
+			expected: "ababab\n",
+		},
+		{
+			name:     "List",
+			pkg:      "strings",
+			function: "Join",
+			envArgs:  true,
+			args:     []string{"env:GORRAM_TEST_LIST", "+"},
+			expected: "a+b\n",
+		},
+		{
+			name:     "Variadic",
+			pkg:      "path",
+			function: "Join",
+			args:     []string{"x", "@" + f.Name()},
+			expected: "x/ab\n",
+		},
+		{
+			name:     "Src",
+			pkg:      "encoding/json",
+			function: "Indent",
+			args:     []string{"-", "", "\t"},
+			stdin:    `{"a": 1}`,
+			expected: "{\n\t\"a\": 1\n}\n",
+		},
+	}
+	// the subtests are in a group so that the file isn't removed until they're
+	// all done.
+	t.Run("group", func(t *testing.T) {
+		for _, test := range tests {
+			test := test
+			t.Run(test.name, func(t *testing.T) {
+				t.Parallel()
+				dir, err := ioutil.TempDir("", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.Remove(dir)
+				stderr := &bytes.Buffer{}
+				stdout := &bytes.Buffer{}
+				env := Env{
+					Stderr: stderr,
+					Stdout: stdout,
+					Stdin:  strings.NewReader(test.stdin),
+				}
+				c := &Command{
+					Package:  test.pkg,
+					Function: test.function,
+					Args:     test.args,
+					EnvArgs:  test.envArgs,
+					Cache:    dir,
+					Env:      env,
+				}
+				err = Run(c)
+				checkRunErr(err, c.script(), t)
+				out := stdout.String()
+				if out != test.expected {
+					t.Errorf("Expected %q but got %q", test.expected, out)
+				}
+				if msg := stderr.String(); msg != "" {
+					t.Errorf("Expected no stderr output but got %q", msg)
+				}
+			})
+		}
+	})
+}
+
+// func Repeat(s string, count int) string
+// Tests the errors for bad indirect arguments.
+func TestIndirectArgErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "UnsetEnv",
+			args:     []string{"ab", "env:GORRAM_TEST_UNSET"},
+			expected: "Environment variable GORRAM_TEST_UNSET is not set.",
+		},
+		{
+			name:     "MissingFile",
+			args:     []string{"@/does/not/exist", "2"},
+			expected: "no such file or directory",
+		},
+		{
+			name:     "StdinTwice",
+			args:     []string{"-", "-"},
+			expected: "Only one argument may be read from stdin.",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: &bytes.Buffer{},
+				Stdin:  strings.NewReader("2"),
+			}
+			c := &Command{
+				Package:  "strings",
+				Function: "Repeat",
+				Args:     test.args,
+				EnvArgs:  true,
+				Cache:    dir,
+				Env:      env,
+			}
+			if err := Run(c); err == nil {
+				t.Fatal("Expected an error but got none")
+			}
+			if msg := stderr.String(); !strings.Contains(msg, test.expected) {
+				t.Errorf("Expected stderr to contain %q but got %q", test.expected, msg)
+			}
+		})
+	}
+}
+
+// Tests arguments given as Go quoted strings with -q.
+func TestQuotedArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		quoted   bool
+		args     []string
+		expected string
+	}{
+		{
+			name:     "Escapes",
+			quoted:   true,
+			args:     []string{`"a\tb"`, "2"},
+			expected: "a\tba\tb\n",
+		},
+		{
+			name:     "Unicode",
+			quoted:   true,
+			args:     []string{`"\u00e9"`, `"3"`},
+			expected: "ééé\n",
+		},
+		{
+			name:     "Raw",
+			quoted:   true,
+			args:     []string{"`a\\t`", "2"},
+			expected: "a\\ta\\t\n",
+		},
+		{
+			name:     "NotQuoted",
+			quoted:   true,
+			args:     []string{"a\\t", "2"},
+			expected: "a\\ta\\t\n",
+		},
+		{
+			name:     "Off",
+			args:     []string{`"a"`, "2"},
+			expected: `"a""a"` + "\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package:  "strings",
+				Function: "Repeat",
+				Args:     test.args,
+				Quoted:   test.quoted,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// Tests that a GORRAM_ variable left in the environment doesn't override the
+// flags given to this command.  It isn't parallel, since it sets the
+// environment for the whole process.
+func TestStaleScriptEnv(t *testing.T) {
+	os.Setenv("GORRAM_QUOTED", "1")
+	defer os.Unsetenv("GORRAM_QUOTED")
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "strings",
+		Function: "Repeat",
+		Args:     []string{`"a"`, "2"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	expected := `"a""a"` + "\n"
+	if out := stdout.String(); out != expected {
+		t.Errorf("Expected %q but got %q", expected, out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests []byte and [N]byte arguments given as hex: or base64:.
+func TestByteArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pkg      string
+		function string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "Hex",
+			pkg:      "bytes",
+			function: "Equal",
+			args:     []string{"-", "hex:6869"},
+			stdin:    "hi",
+			expected: "true\n",
+		},
+		{
+			name:     "Base64",
+			pkg:      "bytes",
+			function: "Equal",
+			args:     []string{"-", "base64:aGk="},
+			stdin:    "hi",
+			expected: "true\n",
+		},
+		{
+			name:     "ArrayHex",
+			pkg:      "net/netip",
+			function: "AddrFrom4",
+			args:     []string{"hex:7f000001"},
+			expected: "127.0.0.1\n",
+		},
+		{
+			name:     "ArrayBase64",
+			pkg:      "net/netip",
+			function: "AddrFrom4",
+			args:     []string{"base64:fwAAAQ"},
+			expected: "127.0.0.1\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+				Stdin:  strings.NewReader(test.stdin),
+			}
+			c := &Command{
+				Package:  test.pkg,
+				Function: test.function,
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// func AddrFrom4(addr [4]byte) Addr
+// Tests the errors for bad byte arguments.
+func TestByteArgErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		args     []string
+		quoted   bool
+		expected string
+	}{
+		{
+			name:     "WrongSize",
+			args:     []string{"hex:7f00"},
+			expected: `invalid value "hex:7f00" for addr ([4]byte): got 2 bytes, expected 4`,
+		},
+		{
+			name:     "BadHex",
+			args:     []string{"hex:zz"},
+			expected: `invalid value "hex:zz" for addr ([]byte): encoding/hex: invalid byte`,
+		},
+		{
+			name:     "BadQuote",
+			args:     []string{`"a`},
+			quoted:   true,
+			expected: `Invalid quoted string "a: invalid syntax.`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: &bytes.Buffer{},
+			}
+			c := &Command{
+				Package:  "net/netip",
+				Function: "AddrFrom4",
+				Args:     test.args,
+				Quoted:   test.quoted,
+				Cache:    dir,
+				Env:      env,
+			}
+			if err := Run(c); err == nil {
+				t.Fatal("Expected an error but got none")
+			}
+			if msg := stderr.String(); !strings.Contains(msg, test.expected) {
+				t.Errorf("Expected stderr to contain %q but got %q", test.expected, msg)
+			}
+		})
+	}
+}
+
+// Tests map arguments.
+func TestMapArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		function string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "Pairs",
+			function: "Lookup",
+			args:     []string{"a=1,b=2", "b"},
+			expected: "2\n",
+		},
+		{
+			name:     "RepeatedFlags",
+			function: "Lookup",
+			args:     []string{"--m", "a=1", "--m=b=3", "b"},
+			expected: "3\n",
+		},
+		{
+			name:     "JSON",
+			function: "Lookup",
+			args:     []string{`{"a": 1, "b": 4}`, "b"},
+			expected: "4\n",
+		},
+		{
+			name:     "NamedListValues",
+			function: "Values",
+			args:     []string{"--h", "a=x", "--h", "b=y", "--h", "a=z", "a"},
+			expected: "[x z]\n",
+		},
+		{
+			name:     "NamedListValuesJSON",
+			function: "Values",
+			args:     []string{`{"a": ["x", "z"], "b": "y"}`, "a"},
+			expected: "[x z]\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package:  "npf.io/gorram/run/_testfuncs",
+				Function: test.function,
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			checkRunErr(err, c.script(), t)
+			out := stdout.String()
+			if out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
+// func RotateLeft8(x uint8, k int) uint8
+// Tests sized integer parsing arguments and outputs.
+func TestBitsRotateLeft8(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "math/bits",
+		Function: "RotateLeft8",
+		Args:     []string{"0x81", "1"},
+		Cache:    dir,
+		Env:      env,
+	}
+	err = Run(c)
+	checkRunErr(err, c.script(), t)
+	out := stdout.String()
+	expected := "3\n"
+	if out != expected {
+		t.Errorf("Expected %q but got %q", expected, out)
+	}
+	if msg := stderr.String(); msg != "" {
+		t.Errorf("Expected no stderr output but got %q", msg)
+	}
+}
+
+// Tests that out of range arguments are rejected rather than truncated.
+func TestArgOutOfRange(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	stderr := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	env := Env{
+		Stderr: stderr,
+		Stdout: stdout,
+	}
+	c := &Command{
+		Package:  "math/bits",
+		Function: "RotateLeft8",
+		Args:     []string{"300", "1"},
+		Cache:    dir,
+		Env:      env,
+	}
+	if err := Run(c); err == nil {
+		t.Fatal("Expected an error but got none")
+	}
+	if out := stdout.String(); out != "" {
+		t.Errorf("Expected no stdout output but got %q", out)
+	}
+	expected := `invalid value "300" for x (uint8): value out of range`
+	if msg := stderr.String(); !strings.Contains(msg, expected) {
+		t.Errorf("Expected stderr to contain %q but got %q", expected, msg)
+	}
+}
+
+// func IsUpper(r rune) bool
+// Tests rune arguments given as a character or a code point.
+func TestRuneArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		stderr   string
+	}{
+		{
+			name:     "Char",
+			args:     []string{"A"},
+			expected: "true\n",
+		},
+		{
+			name:     "Lower",
+			args:     []string{"a"},
+			expected: "false\n",
+		},
+		{
+			name:     "MultiByte",
+			args:     []string{"Ä"},
+			expected: "true\n",
+		},
+		{
+			name:     "CodePoint",
+			args:     []string{"0x41"},
+			expected: "true\n",
+		},
+		{
+			name:   "Word",
+			args:   []string{"Abc"},
+			stderr: `invalid value "Abc" for r (rune): expected a single character or a code point`,
+		},
+		{
+			name:   "Empty",
+			args:   []string{""},
+			stderr: `invalid value "" for r (rune): expected a single character`,
+		},
+		{
+			name:   "Surrogate",
+			args:   []string{"0xD800"},
+			stderr: `invalid value "0xD800" for r (rune): 0xd800 is not a valid code point`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(dir)
+			stderr := &bytes.Buffer{}
+			stdout := &bytes.Buffer{}
+			env := Env{
+				Stderr: stderr,
+				Stdout: stdout,
+			}
+			c := &Command{
+				Package:  "unicode",
+				Function: "IsUpper",
+				Args:     test.args,
+				Cache:    dir,
+				Env:      env,
+			}
+			err = Run(c)
+			if test.stderr != "" {
+				if err == nil {
+					t.Fatal("Expected an error but got none")
+				}
+				if msg := stderr.String(); !strings.Contains(msg, test.stderr) {
+					t.Errorf("Expected stderr to contain %q but got %q", test.stderr, msg)
+				}
+				return
+			}
+			checkRunErr(err, c.script(), t)
+			if out := stdout.String(); out != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, out)
+			}
+			if msg := stderr.String(); msg != "" {
+				t.Errorf("Expected no stderr output but got %q", msg)
+			}
+		})
+	}
+}
+
 // func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error
 // Tests stdin to []byte argument.
 // Tests a dst *bytes.Buffer with a []byte src.
@@ -0,0 +1,110 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// isConstNameFunc is the helper the script uses to decide whether a CLI arg
+// that matched none of the constants was meant to be a constant name (and thus
+// is an error) or a literal value.
+const isConstNameFunc = `
+func isConstName(s string) bool {
+	for i, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+`
+
+// constsOf returns the exported constants declared in pkg that can be passed
+// as an argument of type t: those of exactly the type t, and untyped numeric
+// constants (e.g. http.StatusNotFound) whose value fits in it.  It also returns
+// the ones to suggest to the user, which are just those of type t if there are
+// any, since the untyped ones may be for anything, like crypto/tls's versions
+// among its uint16 cipher suites.
+func (c *Command) constsOf(pkg *types.Package, t types.Type) (consts, suggested []*types.Const) {
+	scope := pkg.Scope()
+	// Names is sorted, so this gives us consistent output.
+	for _, name := range scope.Names() {
+		k, ok := scope.Lookup(name).(*types.Const)
+		if !ok || !k.Exported() {
+			continue
+		}
+		switch {
+		case types.Identical(k.Type(), t):
+			consts = append(consts, k)
+			suggested = append(suggested, k)
+		case untypedFits(k, t):
+			consts = append(consts, k)
+		}
+	}
+	if len(suggested) == 0 {
+		suggested = consts
+	}
+	return consts, suggested
+}
+
+// untypedFits reports whether k is an untyped numeric constant that may be
+// used as a value of t, whose underlying type is a number.
+func untypedFits(k *types.Const, t types.Type) bool {
+	kt, ok := k.Type().(*types.Basic)
+	if !ok || kt.Info()&types.IsUntyped == 0 || kt.Info()&types.IsNumeric == 0 {
+		return false
+	}
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok || b.Info()&types.IsNumeric == 0 {
+		return false
+	}
+	return constantFits(k.Val(), b) == ""
+}
+
+// constConverter wraps base in a conversion function named fn that first
+// checks whether the CLI arg is the name of one of the given constants of type
+// t, and only falls back to base if it is not.  Only the suggested constants
+// are listed in the usage and error messages.
+func (c *Command) constConverter(fn string, t types.Type, consts, suggested []*types.Const, base converter) converter {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "\nfunc %s(s, name string) %s {\n\tswitch s {\n", fn, c.scriptType(t))
+	for _, k := range consts {
+		fmt.Fprintf(buf, "\tcase %q:\n\t\treturn %s\n", k.Name(), c.qualifiedName(k))
+	}
+	names := make([]string, len(suggested))
+	for i, k := range suggested {
+		names[i] = k.Name()
+	}
+	fmt.Fprint(buf, "\t}\n")
+
+	funcs := base.Funcs
+	imports := base.Imports
+	// Only integers get an error for unknown names, since strings can be
+	// anything, and floats and bools have their own special words (e.g. NaN
+	// and true).
+	if b, ok := base.Type.Underlying().(*types.Basic); ok && b.Info()&types.IsInteger != 0 {
+		fmt.Fprintf(buf, `	if isConstName(s) {
+		log.Fatalf("unknown value %%q for %%s (%s), expected a number or one of: %s", s, name)
+	}
+`, typeString(t), strings.Join(names, ", "))
+		funcs = append(funcs, isConstNameFunc)
+		imports = append(imports, "unicode", "log")
+	}
+	fmt.Fprintf(buf, "\treturn %s\n}\n", fmt.Sprintf(base.Expr, "s", "name"))
+
+	// don't let the usage message get too long for packages with lots of
+	// constants.
+	examples := names
+	if len(examples) > 3 {
+		examples = append(examples[:3:3], "...")
+	}
+	return converter{
+		Type:    base.Type,
+		Expr:    fn + "(%[1]s, %[2]s)",
+		Imports: append(imports, consts[0].Pkg().Path()),
+		Funcs:   append([]string{buf.String()}, funcs...),
+		Usage:   "a value or one of " + strings.Join(examples, ", "),
+	}
+}
@@ -0,0 +1,390 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"hash/fnv"
+	"math"
+	"runtime"
+	"strings"
+)
+
+// exprFlag marks the CLI arg after it as a Go expression to use for a param,
+// rather than a value to convert.
+const exprFlag = "-e"
+
+// cliArg is one of the args given for the function.
+type cliArg struct {
+	Text string
+	// Expr is true if the arg was given with -e.
+	Expr bool
+}
+
+// cliArgs returns the args for the function, with the Go expressions given
+// with -e marked as such.  Args after -- are never expressions.
+func (c *Command) cliArgs() ([]cliArg, error) {
+	var args []cliArg
+	for i := 0; i < len(c.Args); i++ {
+		switch c.Args[i] {
+		case "--":
+			for _, a := range c.Args[i:] {
+				args = append(args, cliArg{Text: a})
+			}
+			return args, nil
+		case exprFlag:
+			if i+1 == len(c.Args) {
+				return nil, errors.New("missing expression after -e")
+			}
+			i++
+			args = append(args, cliArg{Text: c.Args[i], Expr: true})
+		default:
+			args = append(args, cliArg{Text: c.Args[i]})
+		}
+	}
+	return args, nil
+}
+
+// scriptArgs returns the args to pass to the script.  Expressions are compiled
+// into the script, so they are left out.
+func (c *Command) scriptArgs() []string {
+	args, err := c.cliArgs()
+	if err != nil {
+		// Generate would have failed.
+		return c.Args
+	}
+	var out []string
+	for _, a := range args {
+		if !a.Expr {
+			out = append(out, a.Text)
+		}
+	}
+	return out
+}
+
+// exprKey returns a key that identifies the script for the expressions given,
+// or an empty string if there are none.  Which params the expressions are for
+// depends on where they are given relative to the other args and flags, so
+// that's part of the key too, but the values of the other args are not.
+func (c *Command) exprKey() string {
+	args, err := c.cliArgs()
+	if err != nil {
+		return ""
+	}
+	h := fnv.New64a()
+	// --from changes whether an interface{} param is optional.
+	fmt.Fprintf(h, "%t\x00", c.From != "")
+	hasExpr := false
+	afterDashes := false
+	for _, a := range args {
+		switch {
+		case a.Expr:
+			hasExpr = true
+			fmt.Fprintf(h, "-e %s\x00", a.Text)
+		case afterDashes:
+			fmt.Fprint(h, "_\x00")
+		case a.Text == "--":
+			afterDashes = true
+			fmt.Fprint(h, "--\x00")
+		case strings.HasPrefix(a.Text, "--"):
+			name, _, hasVal := strings.Cut(a.Text, "=")
+			fmt.Fprintf(h, "%s %t\x00", name, hasVal)
+		default:
+			fmt.Fprint(h, "_\x00")
+		}
+	}
+	if !hasExpr {
+		return ""
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// exprSpec is how a param is bound to CLI args, like the script's argSpec.
+type exprSpec struct {
+	param    int
+	name     string
+	optional bool
+	variadic bool
+	fields   []string
+}
+
+// bindExprs figures out which params the expressions given with -e are for,
+// binding the args the same way the script's bindArgs does, and returns the
+// expressions by param index.  If pipe is true, the input to pipe through the
+// function's result may be given after its args.
+func (c *Command) bindExprs(params *types.Tuple, variadic bool, dst, src int, pipe bool) (map[int]string, error) {
+	args, err := c.cliArgs()
+	if err != nil {
+		return nil, err
+	}
+	hasExpr := false
+	for _, a := range args {
+		hasExpr = hasExpr || a.Expr
+	}
+	if !hasExpr {
+		return nil, nil
+	}
+
+	var specs []exprSpec
+	stdinFree := src == -1 && !pipe
+	for x := 0; x < params.Len(); x++ {
+		p := params.At(x)
+		if x == dst || c.isContext(p.Type()) {
+			continue
+		}
+		spec := exprSpec{
+			param:    x,
+			name:     paramName(p, x),
+			variadic: variadic && x == params.Len()-1,
+		}
+		switch conv, ok := c.argConverter(p.Type()); {
+		case x == src:
+			spec.optional = true
+		case !ok:
+			// only an expression will do for this param.
+		case stdinFree && !spec.variadic && types.Identical(p.Type(), anyType):
+			spec.optional = c.From != ""
+			stdinFree = false
+		case conv.Struct:
+			spec.optional = true
+			stdinFree = false
+			for _, f := range conv.Fields {
+				spec.fields = append(spec.fields, f.Name)
+			}
+		}
+		specs = append(specs, spec)
+	}
+	if pipe {
+		specs = append(specs, exprSpec{param: -1, name: "src", optional: true})
+	}
+
+	flagged := make([]bool, len(specs))
+	var positional []cliArg
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if !a.Expr && a.Text == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if !a.Expr && strings.HasPrefix(a.Text, "--") {
+			name, _, hasVal := strings.Cut(strings.TrimPrefix(a.Text, "--"), "=")
+			if idx := findExprFlag(specs, name); idx != -1 {
+				flagged[idx] = true
+				if !hasVal {
+					if i+1 < len(args) && args[i+1].Expr {
+						return nil, fmt.Errorf("an expression can't be the value of --%s", name)
+					}
+					i++
+				}
+				continue
+			}
+		}
+		positional = append(positional, a)
+	}
+
+	required, most := 0, 0
+	for x, spec := range specs {
+		switch {
+		case flagged[x]:
+		case spec.variadic:
+			most = -1
+		case spec.optional:
+			if most != -1 {
+				most++
+			}
+		default:
+			required++
+			if most != -1 {
+				most++
+			}
+		}
+	}
+	if len(positional) < required || (most != -1 && len(positional) > most) {
+		return nil, fmt.Errorf("can't tell which params the expressions are for, since %s takes %d to %d arguments, but got %d", c.Function, required, most, len(positional))
+	}
+
+	exprs := map[int]string{}
+	extra := len(positional) - required
+	p := 0
+	for x, spec := range specs {
+		switch {
+		case flagged[x]:
+			continue
+		case spec.variadic:
+			for _, a := range positional[p:] {
+				if a.Expr && len(positional)-p > 1 {
+					return nil, fmt.Errorf("an expression for variadic param %s must be its only argument", spec.name)
+				}
+			}
+			if p < len(positional) && positional[p].Expr {
+				exprs[spec.param] = positional[p].Text
+			}
+			p = len(positional)
+			continue
+		case spec.optional && extra == 0:
+			continue
+		case spec.optional:
+			extra--
+		}
+		if positional[p].Expr && spec.param == -1 {
+			return nil, errors.New("an expression can't be the input to pipe through the function's result")
+		}
+		if positional[p].Expr {
+			exprs[spec.param] = positional[p].Text
+		}
+		p++
+	}
+	return exprs, nil
+}
+
+// findExprFlag returns the index of the spec the --name flag is for, or -1 if
+// there isn't one, like the script's findFlag.
+func findExprFlag(specs []exprSpec, name string) int {
+	idx := -1
+	for x, spec := range specs {
+		if spec.name == name {
+			return x
+		}
+		for _, f := range spec.fields {
+			if spec.name+"."+f == name {
+				return x
+			}
+			if f == name && idx == -1 {
+				idx = x
+			}
+		}
+	}
+	return idx
+}
+
+// checkExpr type checks the Go expression given for a param of type t, and
+// returns the imports it needs.  Only packages loaded for the function may be
+// used in the expression.
+func (c *Command) checkExpr(expr, name string, t types.Type) ([]string, error) {
+	tv, imports, err := c.evalExpr(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q for %s: %v", expr, name, err)
+	}
+	if !tv.IsValue() {
+		return nil, fmt.Errorf("invalid expression %q for %s: not a value", expr, name)
+	}
+	if !types.AssignableTo(tv.Type, t) {
+		return nil, fmt.Errorf("invalid expression %q for %s: %s is not assignable to %s", expr, name, typeString(tv.Type), typeString(t))
+	}
+	if tv.Value != nil {
+		// AssignableTo only checks the constant's kind, not its value.
+		target := t
+		if types.IsInterface(t) {
+			target = types.Default(tv.Type)
+		}
+		if b, ok := target.Underlying().(*types.Basic); ok {
+			if why := constantFits(tv.Value, b); why != "" {
+				return nil, fmt.Errorf("invalid expression %q for %s: constant %s %s", expr, name, why, typeString(target))
+			}
+		}
+	}
+	return imports, nil
+}
+
+// constantFits returns why the constant v can't be given as a value of the
+// basic type b, or an empty string if it can.
+func constantFits(v constant.Value, b *types.Basic) string {
+	switch {
+	case b.Info()&types.IsInteger != 0:
+		i := constant.ToInt(v)
+		if i.Kind() != constant.Int {
+			return "is truncated to"
+		}
+		bits := uint(types.SizesFor("gc", runtime.GOARCH).Sizeof(b) * 8)
+		one := constant.MakeInt64(1)
+		min, max := constant.MakeInt64(0), constant.BinaryOp(constant.Shift(one, token.SHL, bits), token.SUB, one)
+		if b.Info()&types.IsUnsigned == 0 {
+			max = constant.BinaryOp(constant.Shift(one, token.SHL, bits-1), token.SUB, one)
+			min = constant.UnaryOp(token.SUB, constant.Shift(one, token.SHL, bits-1), 0)
+		}
+		if constant.Compare(i, token.LSS, min) || constant.Compare(i, token.GTR, max) {
+			return "overflows"
+		}
+	case b.Info()&types.IsFloat != 0:
+		return floatFits(constant.ToFloat(v), b.Kind() == types.Float32)
+	case b.Info()&types.IsComplex != 0:
+		c := constant.ToComplex(v)
+		if c.Kind() != constant.Complex {
+			return "is truncated to"
+		}
+		f32 := b.Kind() == types.Complex64
+		if why := floatFits(constant.Real(c), f32); why != "" {
+			return why
+		}
+		return floatFits(constant.Imag(c), f32)
+	}
+	return ""
+}
+
+// floatFits returns why the constant v can't be given as a float64, or a
+// float32 if f32 is true, or an empty string if it can.
+func floatFits(v constant.Value, f32 bool) string {
+	if v.Kind() != constant.Float && v.Kind() != constant.Int {
+		return "is truncated to"
+	}
+	f, _ := constant.Float64Val(v)
+	if f32 {
+		f32, _ := constant.Float32Val(v)
+		f = float64(f32)
+	}
+	if math.IsInf(f, 0) {
+		return "overflows"
+	}
+	return ""
+}
+
+// evalExpr type checks the Go expression (or type), which may use the packages
+// loaded for the function, and returns its type and the imports it needs.
+func (c *Command) evalExpr(expr string) (types.TypeAndValue, []string, error) {
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		return types.TypeAndValue{}, nil, err
+	}
+	pkgs := map[string]*types.Package{}
+	for _, pkg := range c.loadedPkgs() {
+		pkgs[pkg.Name()] = pkg
+	}
+
+	// the expression is checked in the scope of a package that imports the
+	// packages it uses.
+	scope := types.NewPackage("main", "main")
+	var imports []string
+	var unknown string
+	ast.Inspect(e, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		id, ok := sel.X.(*ast.Ident)
+		if !ok || scope.Scope().Lookup(id.Name) != nil {
+			return true
+		}
+		pkg, ok := pkgs[id.Name]
+		if !ok {
+			if unknown == "" && types.Universe.Lookup(id.Name) == nil {
+				unknown = id.Name
+			}
+			return true
+		}
+		scope.Scope().Insert(types.NewPkgName(token.NoPos, scope, id.Name, pkg))
+		imports = append(imports, pkg.Path())
+		return true
+	})
+	if unknown != "" {
+		return types.TypeAndValue{}, nil, fmt.Errorf("unknown package %s, only packages used by %s may be used", unknown, c.Package)
+	}
+	tv, err := types.Eval(token.NewFileSet(), scope, token.NoPos, expr)
+	if err != nil {
+		return types.TypeAndValue{}, nil, err
+	}
+	return tv, imports, nil
+}
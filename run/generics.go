@@ -0,0 +1,452 @@
+package run
+
+import (
+	"fmt"
+	"go/types"
+	"hash/fnv"
+	"strings"
+)
+
+// typeArgsKey returns a key that identifies the script for the type args
+// given, or an empty string if there are none.
+func (c *Command) typeArgsKey() string {
+	if len(c.TypeArgs) == 0 {
+		return ""
+	}
+	h := fnv.New64a()
+	for _, a := range c.TypeArgs {
+		fmt.Fprintf(h, "%s\x00", a)
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// instantiate returns the signature of the generic function f instantiated
+// with the type args given for it, inferring any that weren't given from the
+// types of the Go expressions given for params and from the constraints of
+// the type params, e.g. S ~[]E.  It also returns the type args and the
+// imports they need.
+func (c *Command) instantiate(f *types.Func) (*types.Signature, []types.Type, []string, error) {
+	sig := f.Type().(*types.Signature)
+	tparams := sig.TypeParams()
+	var given []types.Type
+	var imports []string
+	for _, a := range c.TypeArgs {
+		tv, imps, err := c.evalExpr(a)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid type %q for %s: %v", a, c.Function, err)
+		}
+		if !tv.IsType() {
+			return nil, nil, nil, fmt.Errorf("invalid type %q for %s: not a type", a, c.Function)
+		}
+		given = append(given, tv.Type)
+		imports = append(imports, imps...)
+	}
+	if len(given) > tparams.Len() {
+		return nil, nil, nil, fmt.Errorf("%s has %d type params, but got %d type args", c.Function, tparams.Len(), len(given))
+	}
+
+	// the type args given are for the first type params, like in Go, or, if
+	// that doesn't work, for the ones that the others are derived from, so
+	// e.g. slices.Max[int] works as well as slices.Max[[]int].
+	orders := [][]*types.TypeParam{make([]*types.TypeParam, tparams.Len())}
+	for i := 0; i < tparams.Len(); i++ {
+		orders[0][i] = tparams.At(i)
+	}
+	if len(given) > 0 && len(given) < tparams.Len() {
+		var independent []*types.TypeParam
+		for i := 0; i < tparams.Len(); i++ {
+			if !derived(tparams.At(i), tparams) {
+				independent = append(independent, tparams.At(i))
+			}
+		}
+		if len(independent) >= len(given) {
+			orders = append(orders, independent)
+		}
+	}
+	// errors from instantiating are more useful than failing to infer.
+	var inferErr, instErr error
+	for _, order := range orders {
+		u := newUnifier(tparams)
+		for i, t := range given {
+			u.bound[order[i]] = t
+		}
+		targs, err := c.infer(sig, u)
+		if err != nil {
+			if inferErr == nil {
+				inferErr = err
+			}
+			continue
+		}
+		inst, err := types.Instantiate(nil, sig, targs, true)
+		if err != nil {
+			if instErr == nil {
+				instErr = fmt.Errorf("can't instantiate %s with [%s]: %v", c.Function, typeStrings(targs), err)
+			}
+			continue
+		}
+		for _, t := range targs {
+			imports = append(imports, typeImports(t)...)
+		}
+		return inst.(*types.Signature), targs, imports, nil
+	}
+	if instErr != nil {
+		return nil, nil, nil, instErr
+	}
+	return nil, nil, nil, inferErr
+}
+
+// infer fills in the type params that weren't given from the expressions given
+// for params and from the type params' constraints, and returns the type args
+// for all of the function's type params.  Like Go, it uses the typed
+// expressions first, then the constraints, and only then the untyped constants,
+// which give their default type, or that of the largest kind of constant given
+// for the type param, so cmp.Compare -e 2 -e 2.5 is for float64.
+func (c *Command) infer(sig *types.Signature, u *unifier) ([]types.Type, error) {
+	dst, src, err := c.checkSrcDst(sig)
+	if err != nil {
+		return nil, err
+	}
+	_, _, pipe := c.pipe(sig, dst)
+	exprs, err := c.bindExprs(sig.Params(), sig.Variadic(), dst, src, pipe && src == -1)
+	if err != nil {
+		return nil, err
+	}
+	tparams := sig.TypeParams()
+	untyped := map[*types.TypeParam]*types.Basic{}
+	for x := 0; x < sig.Params().Len(); x++ {
+		expr, ok := exprs[x]
+		if !ok {
+			continue
+		}
+		tv, _, err := c.evalExpr(expr)
+		if err != nil || !tv.IsValue() {
+			// reported when the expression is checked against the
+			// instantiated param.
+			continue
+		}
+		p := sig.Params().At(x)
+		if b, ok := tv.Type.(*types.Basic); ok && b.Info()&types.IsUntyped != 0 {
+			// untyped constants can only give a type param its type.
+			tp, ok := types.Unalias(p.Type()).(*types.TypeParam)
+			if !ok || !u.tparams[tp] {
+				continue
+			}
+			if prev, ok := untyped[tp]; ok && prev != b {
+				if !isNumeric(prev) || !isNumeric(b) {
+					return nil, fmt.Errorf("can't infer %s for %s%s: mismatched types %s and %s", tp.Obj().Name(), c.Function, typeParamsString(tparams), prev, b)
+				}
+				if prev.Kind() > b.Kind() {
+					b = prev
+				}
+			}
+			untyped[tp] = b
+			continue
+		}
+		if !u.unify(p.Type(), tv.Type) {
+			name := paramName(p, x)
+			return nil, fmt.Errorf("can't infer type args for %s%s: %s for %s is %s, but %s is %s", c.Function, typeParamsString(tparams), expr, name, typeString(tv.Type), name, typeString(u.subst(p.Type())))
+		}
+	}
+	if err := c.inferCore(tparams, u); err != nil {
+		return nil, err
+	}
+	bound := false
+	for i := 0; i < tparams.Len(); i++ {
+		tp := tparams.At(i)
+		if _, ok := u.bound[tp]; ok || untyped[tp] == nil {
+			continue
+		}
+		u.bound[tp] = types.Default(untyped[tp])
+		bound = true
+	}
+	if bound {
+		if err := c.inferCore(tparams, u); err != nil {
+			return nil, err
+		}
+	}
+
+	targs := make([]types.Type, tparams.Len())
+	var missing []string
+	for i := 0; i < tparams.Len(); i++ {
+		t, ok := u.bound[tparams.At(i)]
+		if !ok {
+			missing = append(missing, tparams.At(i).Obj().Name())
+		}
+		targs[i] = t
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("can't infer %s for %s%s, give the types in brackets after the function's name, e.g. %s[int]", strings.Join(missing, ", "), c.Function, typeParamsString(tparams), c.Function)
+	}
+	return targs, nil
+}
+
+// inferCore binds the type params that are left from the core types of their
+// constraints, e.g. for [S ~[]E, E any], S gives us E, and E gives us S.  It
+// returns an error if a type param's type doesn't match its core type.
+func (c *Command) inferCore(tparams *types.TypeParamList, u *unifier) error {
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < tparams.Len(); i++ {
+			tp := tparams.At(i)
+			core, tilde, ok := coreType(tp)
+			if !ok {
+				continue
+			}
+			t, ok := u.bound[tp]
+			if !ok {
+				if !u.free(core) {
+					u.bound[tp] = u.subst(core)
+					changed = true
+				}
+				continue
+			}
+			under := t
+			if tilde {
+				under = t.Underlying()
+			}
+			n := len(u.bound)
+			if !u.unify(core, under) {
+				return fmt.Errorf("can't infer type args for %s%s: %s is %s, which doesn't match %s", c.Function, typeParamsString(tparams), tp.Obj().Name(), typeString(t), typeString(u.subst(core)))
+			}
+			changed = changed || len(u.bound) > n
+		}
+	}
+	return nil
+}
+
+// isNumeric reports whether b is a numeric type, like untyped int or float.
+func isNumeric(b *types.Basic) bool {
+	return b.Info()&types.IsNumeric != 0
+}
+
+// derived reports whether tp's constraint has a core type made from other type
+// params, like S ~[]E, so that tp can be inferred from them.
+func derived(tp *types.TypeParam, tparams *types.TypeParamList) bool {
+	core, _, ok := coreType(tp)
+	if !ok {
+		return false
+	}
+	return newUnifier(tparams).free(core)
+}
+
+// coreType returns the single type in tp's constraint, like []E in ~[]E, and
+// whether the constraint allows types with it as their underlying type.
+func coreType(tp *types.TypeParam) (t types.Type, tilde, ok bool) {
+	iface, isIface := tp.Constraint().Underlying().(*types.Interface)
+	if !isIface || iface.NumEmbeddeds() != 1 {
+		return nil, false, false
+	}
+	switch e := iface.EmbeddedType(0).(type) {
+	case *types.Union:
+		if e.Len() != 1 {
+			return nil, false, false
+		}
+		return e.Term(0).Type(), e.Term(0).Tilde(), true
+	case *types.Interface, *types.Named:
+		// e.g. comparable, which doesn't tell us anything.
+		return nil, false, false
+	default:
+		return e, false, true
+	}
+}
+
+// unifier binds a generic function's type params by matching the types of
+// its params up with the types of what's given for them.
+type unifier struct {
+	tparams map[*types.TypeParam]bool
+	bound   map[*types.TypeParam]types.Type
+}
+
+func newUnifier(tparams *types.TypeParamList) *unifier {
+	u := &unifier{
+		tparams: map[*types.TypeParam]bool{},
+		bound:   map[*types.TypeParam]types.Type{},
+	}
+	for i := 0; i < tparams.Len(); i++ {
+		u.tparams[tparams.At(i)] = true
+	}
+	return u
+}
+
+// unify matches x, which may contain type params, with y, binding the type
+// params it finds.  It reports whether they match.
+func (u *unifier) unify(x, y types.Type) bool {
+	x, y = types.Unalias(x), types.Unalias(y)
+	switch x.(type) {
+	case *types.Pointer, *types.Slice, *types.Array, *types.Map, *types.Chan, *types.Signature:
+		// like Go, a type literal matches a named type with the same
+		// underlying type, e.g. []T matches sort.IntSlice.
+		y = y.Underlying()
+	}
+	switch x := x.(type) {
+	case *types.TypeParam:
+		if !u.tparams[x] {
+			break
+		}
+		if t, ok := u.bound[x]; ok {
+			return types.Identical(t, y)
+		}
+		u.bound[x] = y
+		return true
+	case *types.Pointer:
+		y, ok := y.(*types.Pointer)
+		return ok && u.unify(x.Elem(), y.Elem())
+	case *types.Slice:
+		y, ok := y.(*types.Slice)
+		return ok && u.unify(x.Elem(), y.Elem())
+	case *types.Array:
+		y, ok := y.(*types.Array)
+		return ok && x.Len() == y.Len() && u.unify(x.Elem(), y.Elem())
+	case *types.Map:
+		y, ok := y.(*types.Map)
+		return ok && u.unify(x.Key(), y.Key()) && u.unify(x.Elem(), y.Elem())
+	case *types.Chan:
+		y, ok := y.(*types.Chan)
+		return ok && x.Dir() == y.Dir() && u.unify(x.Elem(), y.Elem())
+	case *types.Signature:
+		y, ok := y.(*types.Signature)
+		return ok && x.Variadic() == y.Variadic() && u.unifyTuple(x.Params(), y.Params()) && u.unifyTuple(x.Results(), y.Results())
+	case *types.Named:
+		y, ok := y.(*types.Named)
+		if !ok || x.TypeArgs().Len() == 0 || x.Origin().Obj() != y.Origin().Obj() {
+			break
+		}
+		for i := 0; i < x.TypeArgs().Len(); i++ {
+			if !u.unify(x.TypeArgs().At(i), y.TypeArgs().At(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return types.Identical(x, y)
+}
+
+func (u *unifier) unifyTuple(x, y *types.Tuple) bool {
+	if x.Len() != y.Len() {
+		return false
+	}
+	for i := 0; i < x.Len(); i++ {
+		if !u.unify(x.At(i).Type(), y.At(i).Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+// free reports whether t contains any of the type params that aren't bound.
+func (u *unifier) free(t types.Type) bool {
+	switch t := types.Unalias(t).(type) {
+	case *types.TypeParam:
+		_, ok := u.bound[t]
+		return u.tparams[t] && !ok
+	case *types.Pointer:
+		return u.free(t.Elem())
+	case *types.Slice:
+		return u.free(t.Elem())
+	case *types.Array:
+		return u.free(t.Elem())
+	case *types.Map:
+		return u.free(t.Key()) || u.free(t.Elem())
+	case *types.Chan:
+		return u.free(t.Elem())
+	case *types.Signature:
+		for _, tup := range []*types.Tuple{t.Params(), t.Results()} {
+			for i := 0; i < tup.Len(); i++ {
+				if u.free(tup.At(i).Type()) {
+					return true
+				}
+			}
+		}
+	case *types.Named:
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if u.free(t.TypeArgs().At(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// subst returns t with the bound type params replaced by their types.
+func (u *unifier) subst(t types.Type) types.Type {
+	switch t := types.Unalias(t).(type) {
+	case *types.TypeParam:
+		if b, ok := u.bound[t]; ok {
+			return b
+		}
+	case *types.Pointer:
+		return types.NewPointer(u.subst(t.Elem()))
+	case *types.Slice:
+		return types.NewSlice(u.subst(t.Elem()))
+	case *types.Array:
+		return types.NewArray(u.subst(t.Elem()), t.Len())
+	case *types.Map:
+		return types.NewMap(u.subst(t.Key()), u.subst(t.Elem()))
+	case *types.Chan:
+		return types.NewChan(t.Dir(), u.subst(t.Elem()))
+	case *types.Named:
+		if t.TypeArgs().Len() == 0 {
+			break
+		}
+		args := make([]types.Type, t.TypeArgs().Len())
+		for i := range args {
+			args[i] = u.subst(t.TypeArgs().At(i))
+		}
+		if inst, err := types.Instantiate(nil, t.Origin(), args, false); err == nil {
+			return inst
+		}
+	}
+	return t
+}
+
+// typeImports returns the import paths of the packages of the named types in t.
+func typeImports(t types.Type) []string {
+	var imports []string
+	switch t := types.Unalias(t).(type) {
+	case *types.Pointer:
+		return typeImports(t.Elem())
+	case *types.Slice:
+		return typeImports(t.Elem())
+	case *types.Array:
+		return typeImports(t.Elem())
+	case *types.Map:
+		return append(typeImports(t.Key()), typeImports(t.Elem())...)
+	case *types.Chan:
+		return typeImports(t.Elem())
+	case *types.Signature:
+		for _, tup := range []*types.Tuple{t.Params(), t.Results()} {
+			for i := 0; i < tup.Len(); i++ {
+				imports = append(imports, typeImports(tup.At(i).Type())...)
+			}
+		}
+	case *types.Named:
+		if t.Obj().Pkg() != nil {
+			imports = append(imports, t.Obj().Pkg().Path())
+		}
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			imports = append(imports, typeImports(t.TypeArgs().At(i))...)
+		}
+	}
+	return imports
+}
+
+// typeStrings returns the types as they're shown to the user, separated by
+// commas.
+func typeStrings(ts []types.Type) string {
+	s := make([]string, len(ts))
+	for i, t := range ts {
+		s[i] = typeString(t)
+	}
+	return strings.Join(s, ", ")
+}
+
+// typeParamsString returns the type params as they're declared, e.g.
+// [S ~[]E, E cmp.Ordered].
+func typeParamsString(tparams *types.TypeParamList) string {
+	s := make([]string, tparams.Len())
+	for i := range s {
+		tp := tparams.At(i)
+		s[i] = tp.Obj().Name() + " " + typeString(tp.Constraint())
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
@@ -0,0 +1,357 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"go/token"
+	"go/types"
+	"hash/fnv"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// scriptImports are the names of the packages the script's helpers may import.
+// Globals from other packages with the same names can't be used, since their
+// import would collide with these.
+var scriptImports = map[string]string{
+	"base64":   "encoding/base64",
+	"bufio":    "bufio",
+	"bytes":    "bytes",
+	"context":  "context",
+	"fmt":      "fmt",
+	"hex":      "encoding/hex",
+	"io":       "io",
+	"json":     "encoding/json",
+	"log":      "log",
+	"os":       "os",
+	"signal":   "os/signal",
+	"sort":     "sort",
+	"strconv":  "strconv",
+	"strings":  "strings",
+	"syscall":  "syscall",
+	"template": "text/template",
+	"time":     "time",
+	"unicode":  "unicode",
+}
+
+// global is a package level variable, or function that takes no arguments,
+// that can be used as the value of a param.
+type global struct {
+	// Names are the names the user may give the global by, e.g.
+	// base64.StdEncoding or encoding/base64.StdEncoding.
+	Names []string
+	// Expr is the expression for the value in the script, e.g. sha256.New().
+	Expr string
+	// Pkg is the import path of the global's package.
+	Pkg string
+	// HasError is true for functions that also return an error.
+	HasError bool
+}
+
+// globalConverter handles interfaces, such as hash.Hash or http.Handler,
+// pointers, such as *base64.Encoding, and funcs, by letting the user give the
+// name of a package level variable or a function without arguments that
+// returns a suitable value, e.g. sha256.New or base64.URLEncoding, or, for
+// funcs, a function with the right signature, e.g. unicode.IsSpace.  Only the
+// packages loaded for the function are searched.  The value may also be nil.
+// If base is not nil, it is used to convert values that aren't the name of a
+// global, and is returned as is if there are no globals of the type.
+func (c *Command) globalConverter(t types.Type, base *converter) (converter, bool) {
+	globals := c.globals(t)
+	if base != nil && len(globals) == 0 {
+		return *base, true
+	}
+	fn := "argToGlobal" + typeIdent(t)
+	typeName := c.scriptType(t)
+	names := []string{"nil"}
+	// the prefixes of the names of globals, so we can tell when the user
+	// meant to give one, but got the name wrong.
+	var prefixes []string
+	seen := map[string]bool{}
+	conv := converter{
+		Type:    t,
+		Expr:    fn + "(%[1]s, %[2]s)",
+		Imports: []string{"log"},
+	}
+	// the packages of all the types in t, e.g. hash for hmac.New's
+	// func() hash.Hash.
+	conv.Imports = append(conv.Imports, typeImports(t)...)
+
+	buf := &bytes.Buffer{}
+	if base != nil && base.Multi {
+		// e.g. structs, which may be given by their fields, so the global
+		// can only be given as a single positional arg.
+		fmt.Fprintf(buf, "\nfunc %s(vals []string, name string) %s {\n\tif len(vals) != 1 {\n\t\treturn %s\n\t}\n\ts := vals[0]\n", fn, typeName, fmt.Sprintf(base.Expr, "vals", "name"))
+	} else {
+		fmt.Fprintf(buf, "\nfunc %s(s, name string) %s {\n", fn, typeName)
+	}
+	fmt.Fprint(buf, "\tswitch s {\n\tcase \"nil\":\n\t\treturn nil\n")
+	for _, g := range globals {
+		quoted := make([]string, len(g.Names))
+		for i, n := range g.Names {
+			quoted[i] = fmt.Sprintf("%q", n)
+		}
+		fmt.Fprintf(buf, "\tcase %s:\n", strings.Join(quoted, ", "))
+		if g.HasError {
+			fmt.Fprintf(buf, "\t\tv, err := %s\n\t\tif err != nil {\n\t\t\tlog.Fatalf(\"invalid value %%q for %%s: %%v\", s, name, err)\n\t\t}\n\t\treturn v\n", g.Expr)
+		} else {
+			fmt.Fprintf(buf, "\t\treturn %s\n", g.Expr)
+		}
+		conv.Imports = append(conv.Imports, g.Pkg)
+		names = append(names, g.Names[0])
+		for _, n := range g.Names {
+			i := strings.LastIndex(n, ".")
+			if i == -1 {
+				// a global in the function's own package.
+				continue
+			}
+			prefix := n[:i+1]
+			if !seen[prefix] {
+				seen[prefix] = true
+				prefixes = append(prefixes, fmt.Sprintf("strings.HasPrefix(s, %q)", prefix))
+			}
+		}
+	}
+	fmt.Fprint(buf, "\t}\n")
+	fatal := fmt.Sprintf("log.Fatalf(\"unknown value %%q for %%s (%s), expected one of: %s\", s, name)", typeString(t), strings.Join(names, ", "))
+	if base == nil {
+		fmt.Fprintf(buf, "\t%s\n\treturn nil\n}\n", fatal)
+	} else {
+		vals := "s"
+		if base.Multi {
+			vals = "vals"
+		}
+		fmt.Fprintf(buf, "\tif %s {\n\t\t%s\n\t}\n\treturn %s\n}\n", strings.Join(prefixes, " || "), fatal, fmt.Sprintf(base.Expr, vals, "name"))
+		conv.Imports = append(conv.Imports, "strings")
+	}
+	conv.Funcs = append(conv.Funcs, buf.String())
+
+	// don't let the usage message get too long for types with lots of
+	// candidates.
+	examples := names
+	if len(examples) > 4 {
+		examples = append(examples[:4:4], "...")
+	}
+	conv.Usage = "one of " + strings.Join(examples, ", ")
+	if base != nil {
+		conv.Imports = append(conv.Imports, base.Imports...)
+		conv.Funcs = append(conv.Funcs, base.Funcs...)
+		conv.Usage = joinDesc(conv.Usage, "or "+base.Usage)
+		conv.Multi = base.Multi
+		conv.NoSplit = base.NoSplit
+		conv.Struct = base.Struct
+		conv.Fields = base.Fields
+	}
+	return conv, true
+}
+
+// globals returns the package level variables and functions without arguments
+// in the loaded packages that can be used as a value of type t, sorted by
+// package path and then name.  Globals in the function's own package may also
+// be given without their package name.
+func (c *Command) globals(t types.Type) []global {
+	var globals []global
+	for _, pkg := range c.loadedPkgs() {
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			g, ok := c.globalFor(scope.Lookup(name), t)
+			if !ok {
+				continue
+			}
+			g.Names = []string{pkg.Name() + "." + name}
+			if pkg.Path() != pkg.Name() {
+				g.Names = append(g.Names, pkg.Path()+"."+name)
+			}
+			if pkg == c.pkg() {
+				g.Names = append(g.Names, name)
+			}
+			g.Pkg = pkg.Path()
+			globals = append(globals, g)
+		}
+	}
+	return globals
+}
+
+// loadedPkgs returns the packages loaded for the function that the script may
+// import, sorted by path.  Packages with the same name can't both be imported,
+// so the function's package and the script's own imports win, then the first
+// by path.
+func (c *Command) loadedPkgs() []*types.Package {
+	var pkgs []*types.Package
+	seen := map[string]bool{}
+	for pkg := range c.prog.AllPackages {
+		// the loader may have more than one copy of a package.
+		if seen[pkg.Path()] {
+			continue
+		}
+		if pkg == c.pkg() || importable(pkg) {
+			seen[pkg.Path()] = true
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Path() < pkgs[j].Path()
+	})
+
+	byName := map[string]string{c.pkg().Name(): c.Package}
+	for name, path := range scriptImports {
+		if _, ok := byName[name]; !ok {
+			byName[name] = path
+		}
+	}
+	var loaded []*types.Package
+	for _, pkg := range pkgs {
+		if path, ok := byName[pkg.Name()]; ok && path != pkg.Path() {
+			continue
+		}
+		byName[pkg.Name()] = pkg.Path()
+		loaded = append(loaded, pkg)
+	}
+	return loaded
+}
+
+// globalFor returns the global for obj if it's an exported variable or
+// function without arguments that can be used as a value of type t, or a
+// function that is itself a value of type t.
+func (c *Command) globalFor(obj types.Object, t types.Type) (global, bool) {
+	if !obj.Exported() {
+		return global{}, false
+	}
+	switch obj := obj.(type) {
+	case *types.Var:
+		if types.AssignableTo(obj.Type(), t) {
+			return global{Expr: c.qualifiedName(obj)}, true
+		}
+	case *types.Func:
+		sig := obj.Type().(*types.Signature)
+		if sig.TypeParams().Len() > 0 {
+			return global{}, false
+		}
+		// func params take the function itself, e.g. unicode.IsSpace.
+		if types.AssignableTo(sig, t) {
+			return global{Expr: c.qualifiedName(obj)}, true
+		}
+		if sig.Params().Len() > 0 {
+			return global{}, false
+		}
+		res := sig.Results()
+		switch {
+		case res.Len() == 1 && types.Identical(res.At(0).Type(), errorType):
+			// these are actions, like runtime.StartTrace, not constructors.
+		case res.Len() == 1 && types.AssignableTo(res.At(0).Type(), t):
+			return global{Expr: c.qualifiedName(obj) + "()"}, true
+		case res.Len() == 2 && types.AssignableTo(res.At(0).Type(), t) && hasError(sig):
+			return global{Expr: c.qualifiedName(obj) + "()", HasError: true}, true
+		}
+	}
+	return global{}, false
+}
+
+// importable reports whether the script can import pkg.
+func importable(pkg *types.Package) bool {
+	if pkg.Name() == "main" || pkg.Path() == "unsafe" || pkg.Path() == "C" {
+		return false
+	}
+	for _, elem := range strings.Split(pkg.Path(), "/") {
+		if elem == "internal" || elem == "vendor" || strings.HasPrefix(elem, "_") || strings.HasPrefix(elem, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// argPkgPattern matches an arg that may name a global in another package, like
+// sha256.New or crypto/sha256.New, capturing the package.
+var argPkgPattern = regexp.MustCompile(`^([a-z][\w.\-]*(?:/[\w.\-]+)*)\.[A-Z]\w*$`)
+
+// argPkgs returns the packages named by the args that look like globals,
+// sorted and without duplicates, e.g. sha256 for crypto/hmac New sha256.New.
+// The function's package may not use them, so they need loading too.
+func (c *Command) argPkgs() []string {
+	seen := map[string]bool{}
+	var pkgs []string
+	for _, arg := range c.Args {
+		// --name=value args name their param.
+		if strings.HasPrefix(arg, "--") {
+			if i := strings.Index(arg, "="); i >= 0 {
+				arg = arg[i+1:]
+			}
+		}
+		m := argPkgPattern.FindStringSubmatch(arg)
+		if m == nil || seen[m[1]] {
+			continue
+		}
+		if !strings.Contains(m[1], "/") && !token.IsIdentifier(m[1]) {
+			continue
+		}
+		seen[m[1]] = true
+		pkgs = append(pkgs, m[1])
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// argPkgsKey returns a key that identifies the script for the packages the
+// args name, or an empty string if they name none.
+func (c *Command) argPkgsKey() string {
+	pkgs := c.argPkgs()
+	if len(pkgs) == 0 {
+		return ""
+	}
+	h := fnv.New64a()
+	for _, p := range pkgs {
+		fmt.Fprintf(h, "%s\x00", p)
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// argImports returns the import paths of the packages the args name that can
+// be found.  A bare name like sha256 is looked up in the standard library, and
+// is skipped unless exactly one package there has that name.  Args that don't
+// name a package are left for their converters to reject.
+func (c *Command) argImports() []string {
+	var paths []string
+	for _, pkg := range c.argPkgs() {
+		if strings.Contains(pkg, "/") {
+			if _, err := build.Import(pkg, "", build.FindOnly); err == nil {
+				paths = append(paths, pkg)
+			}
+			continue
+		}
+		if path, ok := stdPkgNamed(pkg); ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// stdPkgNamed returns the path of the only importable standard library package
+// whose directory is name, e.g. crypto/sha256 for sha256.
+func stdPkgNamed(name string) (string, bool) {
+	src := filepath.Join(build.Default.GOROOT, "src")
+	var found []string
+	filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		switch d.Name() {
+		case "internal", "vendor", "testdata", "cmd":
+			return filepath.SkipDir
+		}
+		if d.Name() == name && path != src {
+			rel, err := filepath.Rel(src, path)
+			if err == nil {
+				found = append(found, filepath.ToSlash(rel))
+			}
+		}
+		return nil
+	})
+	if len(found) != 1 {
+		return "", false
+	}
+	return found[0], true
+}
@@ -0,0 +1,256 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// chooseParserFunc is the helper the script uses to pick which of several
+// parsers to use for an argument, based on gorram's -p flag.
+const chooseParserFunc = `
+// chooseParser returns the parser chosen with gorram's -p flag for the named
+// parameter, or the first of the candidates if none was chosen.
+func chooseParser(name string, candidates ...string) string {
+	for _, p := range strings.Split(os.Getenv("GORRAM_PARSERS"), ",") {
+		if p == "" {
+			continue
+		}
+		param, fn := "", p
+		if i := strings.Index(p, "="); i >= 0 {
+			param, fn = p[:i], p[i+1:]
+		}
+		if param != "" && param != name {
+			continue
+		}
+		for _, c := range candidates {
+			if c == fn {
+				return c
+			}
+		}
+		if param != "" {
+			log.Fatalf("unknown parser %q for %s, expected one of: %s", fn, name, strings.Join(candidates, ", "))
+		}
+		// a parser for another param is fine, but one for no param at all is
+		// probably a typo.
+		known := false
+		for _, c := range parserNames {
+			known = known || c == fn
+		}
+		if !known {
+			log.Fatalf("unknown parser %q for -p, expected one of: %s", fn, strings.Join(parserNames, ", "))
+		}
+	}
+	return candidates[0]
+}
+`
+
+// parserNamesDecl declares the names of all the parsers the script's params
+// may choose from, for chooseParser.
+const parserNamesDecl = `
+var parserNames = %#v
+`
+
+// parsersKey returns a key that identifies the script for the parsers given
+// with -p, or an empty string if none were given.
+func (c *Command) parsersKey() string {
+	if len(c.Parsers) == 0 {
+		return ""
+	}
+	h := fnv.New64a()
+	for _, p := range c.Parsers {
+		fmt.Fprintf(h, "%s\x00", p)
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// checkParsers returns an error if a parser chosen with -p for no param in
+// particular isn't one of the choices for any of the function's params, which
+// parserConverter recorded.  Parsers chosen for a param are checked by the
+// script, which knows which choices each param has.
+func (c *Command) checkParsers() error {
+	// sorted for consistent output.
+	sort.Strings(c.parserNames)
+	for _, arg := range c.Parsers {
+		for _, fn := range strings.Split(arg, ",") {
+			if fn == "" || strings.Contains(fn, "=") {
+				continue
+			}
+			found := false
+			for _, name := range c.parserNames {
+				found = found || name == fn
+			}
+			switch {
+			case found:
+			case len(c.parserNames) == 0:
+				return fmt.Errorf("unknown parser %q for -p, %s.%s has no args with parsers to choose from", fn, c.Package, c.Function)
+			default:
+				return fmt.Errorf("unknown parser %q for -p, expected one of: %s", fn, strings.Join(c.parserNames, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// argParser is one way of creating a value of some type from a string.
+type argParser struct {
+	// Name is the name the user can choose the parser by with -p.
+	Name string
+	// Code sets v (and err, if the parser can fail) from the string s.
+	Code string
+}
+
+// parserConverter handles types that know how to parse themselves, because
+// they (or a pointer to them) implement encoding.TextUnmarshaler, such as
+// net.IP or *big.Int, and types whose package has a function that creates the
+// type from a string, such as regexp.Compile or url.Parse.  If there is more
+// than one way to parse the type, the user may choose which one to use with
+// gorram's -p flag.
+func (c *Command) parserConverter(t types.Type) (converter, bool) {
+	named, ok := exportedNamed(t)
+	if !ok {
+		return converter{}, false
+	}
+	parsers := c.parsers(t)
+	if len(parsers) == 0 {
+		return converter{}, false
+	}
+	fn := convFuncName(t)
+	typeName := c.scriptType(t)
+	conv := converter{
+		Type:    t,
+		Expr:    fn + "(%[1]s, %[2]s)",
+		Imports: []string{"log", named.Obj().Pkg().Path()},
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "\nfunc %s(s, name string) %s {\n\tvar v %s\n\tvar err error\n", fn, typeName, typeName)
+	if len(parsers) == 1 {
+		fmt.Fprint(buf, parsers[0].Code)
+		conv.Usage = "parsed with " + parsers[0].Name
+	} else {
+		names := make([]string, len(parsers))
+		for i, p := range parsers {
+			names[i] = fmt.Sprintf("%q", p.Name)
+			c.addParserName(p.Name)
+		}
+		var others []string
+		for _, p := range parsers[1:] {
+			others = append(others, p.Name)
+		}
+		conv.Usage = fmt.Sprintf("parsed with %s, or choose with -p from %s", parsers[0].Name, strings.Join(others, ", "))
+		fmt.Fprintf(buf, "\tswitch chooseParser(name, %s) {\n", strings.Join(names, ", "))
+		for _, p := range parsers {
+			fmt.Fprintf(buf, "\tcase %q:\n%s", p.Name, p.Code)
+		}
+		fmt.Fprint(buf, "\t}\n")
+		conv.Funcs = append(conv.Funcs, chooseParserFunc)
+		conv.Imports = append(conv.Imports, "os", "strings")
+	}
+	fmt.Fprintf(buf, `	if err != nil {
+		log.Fatalf("invalid value %%q for %%s (%s): %%v", s, name, err)
+	}
+	return v
+}
+`, typeString(t))
+	conv.Funcs = append([]string{buf.String()}, conv.Funcs...)
+	return conv, true
+}
+
+// addParserName records name as one of the parsers that may be chosen with -p.
+func (c *Command) addParserName(name string) {
+	for _, n := range c.parserNames {
+		if n == name {
+			return
+		}
+	}
+	c.parserNames = append(c.parserNames, name)
+}
+
+// parsers returns the ways we know to parse a string into the named type t (or
+// pointer to a named type), most likely candidate first.  A type's own
+// UnmarshalText method comes first, then functions that return an error
+// (rather than panicking), then ones named Parse, then ones starting with
+// Parse, then the rest alphabetically.
+func (c *Command) parsers(t types.Type) []argParser {
+	var parsers []argParser
+
+	// UnmarshalText always needs a pointer receiver to be useful, so this is
+	// what we need to check even for non-pointer params.
+	if p, isPtr := t.(*types.Pointer); isPtr {
+		if types.Implements(t, c.textUnmarshaler) {
+			parsers = append(parsers, argParser{
+				Name: "UnmarshalText",
+				Code: fmt.Sprintf("\t\tv = new(%s)\n\t\terr = v.UnmarshalText([]byte(s))\n", c.scriptType(p.Elem())),
+			})
+		}
+	} else if types.Implements(types.NewPointer(t), c.textUnmarshaler) {
+		parsers = append(parsers, argParser{
+			Name: "UnmarshalText",
+			Code: "\t\terr = v.UnmarshalText([]byte(s))\n",
+		})
+	}
+
+	for _, f := range parserFuncs(t) {
+		code := fmt.Sprintf("\t\tv = %s(s)\n", c.qualifiedName(f))
+		if hasError(f.Type().(*types.Signature)) {
+			code = fmt.Sprintf("\t\tv, err = %s(s)\n", c.qualifiedName(f))
+		}
+		parsers = append(parsers, argParser{Name: f.Name(), Code: code})
+	}
+	return parsers
+}
+
+// parserFuncs returns the exported functions in the package of the named type
+// t (or the type t points to) that look like func(string) (t, error) or
+// func(string) t, sorted by how likely they are to be the right choice.
+func parserFuncs(t types.Type) []*types.Func {
+	named, ok := exportedNamed(t)
+	if !ok {
+		return nil
+	}
+	scope := named.Obj().Pkg().Scope()
+	var funcs []*types.Func
+	for _, name := range scope.Names() {
+		f, ok := scope.Lookup(name).(*types.Func)
+		if !ok || !f.Exported() {
+			continue
+		}
+		sig := f.Type().(*types.Signature)
+		if sig.TypeParams().Len() > 0 || sig.Variadic() {
+			continue
+		}
+		if sig.Params().Len() != 1 || !types.Identical(sig.Params().At(0).Type(), stringType) {
+			continue
+		}
+		res := sig.Results()
+		switch {
+		case res.Len() == 1 && types.Identical(res.At(0).Type(), t):
+		case res.Len() == 2 && types.Identical(res.At(0).Type(), t) && types.Identical(res.At(1).Type(), errorType):
+		default:
+			continue
+		}
+		funcs = append(funcs, f)
+	}
+	rank := func(f *types.Func) int {
+		r := 0
+		if !hasError(f.Type().(*types.Signature)) {
+			r += 4
+		}
+		switch {
+		case f.Name() == "Parse":
+		case strings.HasPrefix(f.Name(), "Parse"):
+			r++
+		default:
+			r += 2
+		}
+		return r
+	}
+	sort.SliceStable(funcs, func(i, j int) bool {
+		return rank(funcs[i]) < rank(funcs[j])
+	})
+	return funcs
+}
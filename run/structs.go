@@ -0,0 +1,158 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strconv"
+)
+
+// structArgFunc is the helper the script uses to fill in a struct param from
+// the values given for it.
+const structArgFunc = `
+// structArg fills in v, which points to a struct, from the values given for a
+// struct param: JSON or nil.  It returns the values given for each of the
+// struct's fields with --Field=value flags, in the order the fields were first
+// seen, and whether the param was given as nil.
+func structArg(vals []string, name string, v interface{}) (fields []string, fieldVals [][]string, isNil bool) {
+	idx := map[string]int{}
+	for _, s := range vals {
+		if strings.HasPrefix(s, "--") {
+			field, val, _ := strings.Cut(s[2:], "=")
+			i, ok := idx[field]
+			if !ok {
+				i = len(fields)
+				idx[field] = i
+				fields = append(fields, field)
+				fieldVals = append(fieldVals, nil)
+			}
+			fieldVals[i] = append(fieldVals[i], val)
+			continue
+		}
+		if strings.TrimSpace(s) == "nil" {
+			isNil = true
+			continue
+		}
+		if err := json.Unmarshal([]byte(s), v); err != nil {
+			log.Fatalf("invalid value %q for %s: %v", s, name, err)
+		}
+	}
+	return fields, fieldVals, isNil
+}
+`
+
+// orStdinFunc is the helper the script uses to read a struct param from stdin
+// when the function has no other use for stdin.
+const orStdinFunc = `
+// orStdin returns vals, or, if there are none and stdin isn't a terminal,
+// what's on stdin.
+func orStdin(vals []string) []string {
+	if len(vals) > 0 {
+		return vals
+	}
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice != 0 {
+		return vals
+	}
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return vals
+	}
+	return []string{string(b)}
+}
+`
+
+// structConverter handles exported struct types and pointers to them, such as
+// jpeg.Options or *tls.Config.  The struct may be given as JSON, as @ and the
+// name of a file holding JSON, or as nil if it's a pointer, and each exported
+// field whose type we can convert may be set with a --Field=value flag.
+func (c *Command) structConverter(t types.Type) (converter, bool) {
+	named, ok := exportedNamed(t)
+	if !ok {
+		return converter{}, false
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return converter{}, false
+	}
+	_, isPtr := t.(*types.Pointer)
+	for _, in := range c.inStruct {
+		if types.Identical(in, named) {
+			// self-referential structs (e.g. linked lists) can only be given as
+			// JSON.
+			return converter{}, false
+		}
+	}
+	c.inStruct = append(c.inStruct, named)
+	defer func() { c.inStruct = c.inStruct[:len(c.inStruct)-1] }()
+
+	fn := convFuncName(t)
+	typeName := c.scriptType(named)
+	conv := converter{
+		Type:    t,
+		Expr:    fn + "(%[1]s, %[2]s)",
+		Imports: []string{named.Obj().Pkg().Path(), "encoding/json", "log", "strings"},
+		Funcs:   []string{structArgFunc},
+		Usage:   "JSON, or @ and a file of JSON",
+		Multi:   true,
+		NoSplit: true,
+		Struct:  true,
+	}
+	if isPtr {
+		conv.Usage = "JSON, @ and a file of JSON, or nil"
+	}
+
+	cases := &bytes.Buffer{}
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if !f.Exported() {
+			continue
+		}
+		fieldConv, ok := c.argConverter(f.Type())
+		if !ok {
+			// fields we can't convert may still be set with JSON.
+			continue
+		}
+		vals := "vals[len(vals)-1]"
+		if fieldConv.Multi {
+			vals = "vals"
+		}
+		fieldName := "name+" + strconv.Quote("."+f.Name())
+		fmt.Fprintf(cases, "\t\tcase %q:\n\t\t\tv.%s = %s\n", f.Name(), f.Name(), fmt.Sprintf(fieldConv.Expr, vals, fieldName))
+		conv.Imports = append(conv.Imports, fieldConv.Imports...)
+		conv.Funcs = append(conv.Funcs, fieldConv.Funcs...)
+		conv.Fields = append(conv.Fields, usageArg{
+			Name: f.Name(),
+			Type: typeString(f.Type()),
+			Desc: fieldConv.Usage,
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "\nfunc %s(vals []string, name string) %s {\n\tv := new(%s)\n", fn, c.scriptType(t), typeName)
+	if len(conv.Fields) > 0 {
+		fmt.Fprint(buf, "\tfields, fieldVals, isNil := structArg(vals, name, v)\n")
+	} else {
+		fmt.Fprint(buf, "\t_, _, isNil := structArg(vals, name, v)\n")
+	}
+	fmt.Fprint(buf, "\tif isNil {\n")
+	if isPtr {
+		fmt.Fprint(buf, "\t\treturn nil\n")
+	} else {
+		fmt.Fprintf(buf, "\t\tlog.Fatalf(\"invalid value nil for %%s (%s), only pointers may be nil\", name)\n", typeString(named))
+	}
+	fmt.Fprint(buf, "\t}\n")
+	if len(conv.Fields) > 0 {
+		fmt.Fprintf(buf, "\tfor i, field := range fields {\n\t\tvals := fieldVals[i]\n\t\tswitch field {\n%s\t\t}\n\t}\n", cases)
+	}
+	if isPtr {
+		fmt.Fprint(buf, "\treturn v\n}\n")
+	} else {
+		fmt.Fprint(buf, "\treturn *v\n}\n")
+	}
+	conv.Funcs = append([]string{buf.String()}, conv.Funcs...)
+	return conv, true
+}
@@ -7,12 +7,14 @@ package main
 
 import (
 {{range $import, $ignored := .Imports -}}
-	"{{$import}}"
+	{{index $.Aliases $import}} "{{$import}}"
 {{end}}
 )
 
 const version = "{{.Version}}"
 
+const usage = {{printf "%q" .Usage}}
+
 
 func main() {
 	log.SetFlags(0)
@@ -23,6 +25,10 @@ func main() {
 	{{end}}
 	
 	{{.SrcInit}}
+	{{if .HasContext}}
+	ctx, cancel := newContext()
+	defer cancel()
+	{{end}}
 
 	{{if gt .NumCLIArgs 0}}
 	// strip off the executable name and the -- that we put in so that go run
@@ -31,16 +37,20 @@ func main() {
 	if len(os.Args) > 2 {
 		args = os.Args[2:]
 	}
+	vals := bindArgs(args, []argSpec{
+		{{range .ArgSpecs}}{{.}},
+		{{end}}
+	})
 	{{end}}
-	{{if ne .SrcIdx -1}}
-	expectedCLIArgs := {{.NumCLIArgs}}
-	switch len(args) {
-	case expectedCLIArgs - 1:
+	{{if .HasSrc}}
+	if len(vals[{{.SrcArg}}]) == 0{{if not .SrcValue}} || vals[{{.SrcArg}}][0] == "-"{{end}} {
+		if stdinUsed {
+			log.Fatal("Only one argument may be read from stdin.")
+		}
+		stdinUsed = true
 		src = stdinToSrc()
-	case expectedCLIArgs:
-		src, args = argsToSrc(args)
-	default:
-		log.Fatalf("Expected %d or %d arguments, but got %d args.\n\n", expectedCLIArgs-1, expectedCLIArgs, len(args))
+	} else {
+		src = argToSrc(vals[{{.SrcArg}}][0])
 	}
 	{{end}}
 	{{range .ArgInits}}
@@ -51,6 +61,7 @@ func main() {
 
 	{{.Results}}{{.PkgName}}.{{if .GlobalVar}}{{.GlobalVar}}.{{end}}{{.Func}}({{.Args}})
 	{{.ErrCheck}}
+	{{.Pipe}}
 	{{if ne .DstIdx -1}}
 	{{.DstToStdout}}
 	{{else}}
@@ -72,6 +83,273 @@ func main() {
 	{{.PrintVal}}
 	{{end}}
 }
+{{if gt .NumCLIArgs 0}}
+// argSpec describes how CLI args are bound to one of the function's params.
+type argSpec struct {
+	name string
+	// optional params (the src) are skipped if there aren't enough args.
+	optional bool
+	// list params take any number of values, either from repeated --name=value
+	// flags, or a single comma separated arg.  Other params may only be given
+	// once.
+	list bool
+	// noSplit list params get a single arg as is, rather than split on commas.
+	noSplit bool
+	// variadic params take all the remaining args.
+	variadic bool
+	// raw params (a stream src) get their arg as is, since it's already the
+	// name of a file, or - for stdin.
+	raw bool
+	// fields are the names of struct fields that may be set with --Field=value
+	// or --name.Field=value flags.  Their values are added to the param's
+	// values as --Field=value.
+	fields []string
+}
+
+// bindArgs matches the CLI args up with the function's params, returning the
+// values for each param.  Optional params that were skipped have no values.
+func bindArgs(args []string, specs []argSpec) [][]string {
+	vals := make([][]string, len(specs))
+	flagged := make([]bool, len(specs))
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		idx, field := -1, ""
+		name, val, hasVal := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if strings.HasPrefix(arg, "--") {
+			idx, field = findFlag(specs, name)
+		}
+		if idx == -1 {
+			if isFlag(arg) {
+				log.Fatalf("Unknown flag --%s.\n\n%s", name, usage)
+			}
+			positional = append(positional, arg)
+			continue
+		}
+		if flagged[idx] && field == "" && !specs[idx].list {
+			log.Fatalf("Flag --%s given more than once.\n\n%s", name, usage)
+		}
+		if !hasVal {
+			if i+1 == len(args) {
+				log.Fatalf("Missing value for --%s.\n\n%s", name, usage)
+			}
+			i++
+			val = args[i]
+		}
+		if !specs[idx].raw {
+			val = indirect(val)
+		}
+		if field != "" {
+			val = "--" + field + "=" + val
+		}
+		vals[idx] = append(vals[idx], val)
+		flagged[idx] = true
+	}
+
+	// figure out how many positional args we can take.
+	required, most := 0, 0
+	for x, spec := range specs {
+		switch {
+		case flagged[x]:
+		case spec.variadic:
+			most = -1
+		case spec.optional:
+			if most != -1 {
+				most++
+			}
+		default:
+			required++
+			if most != -1 {
+				most++
+			}
+		}
+	}
+	// required params are filled in order, so the ones at the end are the
+	// ones that are missing.
+	missing := ""
+	if len(positional) < required {
+		var names []string
+		n := 0
+		for x, spec := range specs {
+			if flagged[x] || spec.optional || spec.variadic {
+				continue
+			}
+			if n >= len(positional) {
+				names = append(names, spec.name)
+			}
+			n++
+		}
+		missing = fmt.Sprintf("  Missing %s.", strings.Join(names, ", "))
+	}
+	switch {
+	case most == -1:
+		if len(positional) < required {
+			log.Fatalf("Expected at least %d arguments, but got %d args.%s\n\n%s", required, len(positional), missing, usage)
+		}
+	case len(positional) >= required && len(positional) <= most:
+	case most == required:
+		log.Fatalf("Expected %d arguments, but got %d args.%s\n\n%s", required, len(positional), missing, usage)
+	case most == required+1:
+		log.Fatalf("Expected %d or %d arguments, but got %d args.%s\n\n%s", required, most, len(positional), missing, usage)
+	default:
+		log.Fatalf("Expected %d to %d arguments, but got %d args.%s\n\n%s", required, most, len(positional), missing, usage)
+	}
+
+	// optional params are filled in order with whatever args are left over
+	// from the required ones.
+	extra := len(positional) - required
+	p := 0
+	for x, spec := range specs {
+		get := func(arg string) string {
+			if spec.raw {
+				return arg
+			}
+			return indirect(arg)
+		}
+		switch {
+		case flagged[x]:
+		case spec.variadic:
+			for _, arg := range positional[p:] {
+				vals[x] = append(vals[x], get(arg))
+			}
+			p = len(positional)
+		case spec.optional && extra == 0:
+		case spec.noSplit:
+			vals[x] = []string{get(positional[p])}
+			p++
+			if spec.optional {
+				extra--
+			}
+		case spec.list:
+			if arg := get(positional[p]); arg != "" {
+				vals[x] = strings.Split(arg, ",")
+			}
+			p++
+		default:
+			vals[x] = []string{get(positional[p])}
+			p++
+			if spec.optional {
+				extra--
+			}
+		}
+	}
+	return vals
+}
+
+// findFlag returns the index of the param the --name flag is for, and the
+// struct field it sets, if any.  It returns -1 if no param takes the flag.
+// Every param may be given by its name.
+func findFlag(specs []argSpec, name string) (idx int, field string) {
+	idx, matches := -1, 0
+	for x, spec := range specs {
+		if spec.name == name {
+			return x, ""
+		}
+		for _, f := range spec.fields {
+			if spec.name+"."+f == name {
+				return x, f
+			}
+			if f == name {
+				idx, field = x, f
+				matches++
+			}
+		}
+	}
+	if matches > 1 {
+		log.Fatalf("Flag --%s is ambiguous, use --<param>.%s instead.\n\n%s", name, name, usage)
+	}
+	return idx, field
+}
+
+// stdinUsed is set once an arg has been read from stdin, since there's only
+// the one.
+var stdinUsed bool
+
+// indirect returns the value of an arg that may be given indirectly: @path is
+// the contents of the file, env:NAME is the environment variable if -env was
+// given, and - is what's on stdin, less one trailing newline for files and
+// stdin.  @@ escapes a literal @.  With -q, an arg that is a Go quoted string is unquoted instead.
+func indirect(arg string) string {
+	var b []byte
+	var err error
+	switch {
+	case os.Getenv("GORRAM_QUOTED") != "" && arg != "" && (arg[0] == '"' || arg[0] == '\x60'):
+		s, err := strconv.Unquote(arg)
+		if err != nil {
+			log.Fatalf("Invalid quoted string %s: %v.", arg, err)
+		}
+		return s
+	case strings.HasPrefix(arg, "@@"):
+		return arg[1:]
+	case strings.HasPrefix(arg, "@") && len(arg) > 1:
+		b, err = os.ReadFile(arg[1:])
+	case os.Getenv("GORRAM_ENV_ARGS") != "" && strings.HasPrefix(arg, "env:"):
+		v, ok := os.LookupEnv(arg[len("env:"):])
+		if !ok {
+			log.Fatalf("Environment variable %s is not set.", arg[len("env:"):])
+		}
+		return v
+	case arg == "-":
+		if stdinUsed {
+			log.Fatal("Only one argument may be read from stdin.")
+		}
+		stdinUsed = true
+		b, err = io.ReadAll(os.Stdin)
+	default:
+		return arg
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := strings.TrimSuffix(string(b), "\n")
+	return strings.TrimSuffix(s, "\r")
+}
+
+// isFlag reports whether arg looks like a --name or --name=value flag, rather
+// than a value that happens to start with --.
+func isFlag(arg string) bool {
+	name, _, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+	if !strings.HasPrefix(arg, "--") || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && r != '.' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+{{end}}
+{{if .HasContext}}
+// newContext returns the context to pass to the function, which is cancelled on
+// SIGINT or SIGTERM, or when the timeout given with --timeout is up.
+func newContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		// a second signal kills us as usual, in case the function doesn't
+		// stop.
+		<-ctx.Done()
+		stop()
+	}()
+	t := os.Getenv("GORRAM_TIMEOUT")
+	if t == "" {
+		return ctx, stop
+	}
+	d, err := time.ParseDuration(t)
+	if err != nil {
+		log.Fatalf("invalid timeout %q: %v", t, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+{{end}}
 {{.ArgsToSrc}}
 {{.StdinToSrc}}
 {{range .ArgConvFuncs}}